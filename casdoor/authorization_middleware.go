@@ -0,0 +1,162 @@
+package casdoor
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/kataras/iris/v12"
+)
+
+var (
+	// ErrForbidden is the error value that it's returned when
+	// the authenticated user does not satisfy the required authorization.
+	ErrForbidden = errors.New("permission denied")
+)
+
+// Authorization describes the roles,groups and permissions required by an endpoint.
+// every non-empty list must be matched by at least one item of the user's claims,
+// the names can be either "name" or "owner/name"
+type Authorization struct {
+	Roles       []string
+	Groups      []string
+	Permissions []string
+}
+
+// is no requirement
+func (a Authorization) IsEmpty() bool {
+	return len(a.Roles) <= 0 && len(a.Groups) <= 0 && len(a.Permissions) <= 0
+}
+
+// check the claims satisfy the authorization
+func (a Authorization) IsSatisfiedBy(claims *casdoorsdk.Claims) bool {
+	if a.IsEmpty() {
+		return true
+	}
+	if claims == nil {
+		return false
+	}
+	if len(a.Roles) > 0 && !HasAnyRole(claims, a.Roles...) {
+		return false
+	}
+	if len(a.Groups) > 0 && !InAnyGroup(claims, a.Groups...) {
+		return false
+	}
+	if len(a.Permissions) > 0 && !HasAnyPermission(claims, a.Permissions...) {
+		return false
+	}
+	return true
+}
+
+// check the claims have any of roles
+func HasAnyRole(claims *casdoorsdk.Claims, roles ...string) bool {
+	if claims == nil {
+		return false
+	}
+	for _, eachRole := range claims.Roles {
+		if eachRole == nil {
+			continue
+		}
+		if matchAnyName(eachRole.Owner, eachRole.Name, roles) {
+			return true
+		}
+	}
+	return false
+}
+
+// check the claims have any of permissions
+func HasAnyPermission(claims *casdoorsdk.Claims, permissions ...string) bool {
+	if claims == nil {
+		return false
+	}
+	for _, eachPermission := range claims.Permissions {
+		if eachPermission == nil {
+			continue
+		}
+		if matchAnyName(eachPermission.Owner, eachPermission.Name, permissions) {
+			return true
+		}
+	}
+	return false
+}
+
+// check the claims belong to any of groups
+func InAnyGroup(claims *casdoorsdk.Claims, groups ...string) bool {
+	if claims == nil {
+		return false
+	}
+	for _, eachGroup := range claims.Groups {
+		// casdoor group is formatted as owner/name
+		owner, name, found := strings.Cut(eachGroup, "/")
+		if !found {
+			owner, name = "", eachGroup
+		}
+		if matchAnyName(owner, name, groups) {
+			return true
+		}
+	}
+	return false
+}
+
+func matchAnyName(owner string, name string, expectedList []string) bool {
+	for _, eachExpected := range expectedList {
+		if eachExpected == name {
+			return true
+		}
+		if len(owner) > 0 && eachExpected == owner+"/"+name {
+			return true
+		}
+	}
+	return false
+}
+
+// check current user satisfy the authorization,
+// the user's claims must be set by CasdoorMiddleware before
+type MustAuthorized struct {
+	Authorization Authorization
+	// get the claims of current user
+	ClaimsGetter func(iris.Context) *casdoorsdk.Claims
+	// The function that will be called when the user is not authorized
+	// Default value: OnForbidden
+	ErrorHandler errorHandler
+}
+
+func NewMustAuthorized(claimsGetter func(iris.Context) *casdoorsdk.Claims, authorization Authorization) *MustAuthorized {
+	return &MustAuthorized{
+		Authorization: authorization,
+		ClaimsGetter:  claimsGetter,
+		ErrorHandler:  OnForbidden,
+	}
+}
+
+// Serve the middleware's action
+func (m *MustAuthorized) Serve(ctx iris.Context) {
+	var claims *casdoorsdk.Claims
+	if m.ClaimsGetter != nil {
+		claims = m.ClaimsGetter(ctx)
+	}
+	if claims == nil {
+		OnError(ctx, ErrTokenMissing)
+		return
+	}
+	if !m.Authorization.IsSatisfiedBy(claims) {
+		errorHandler := m.ErrorHandler
+		if errorHandler == nil {
+			errorHandler = OnForbidden
+		}
+		errorHandler(ctx, ErrForbidden)
+		return
+	}
+
+	// If everything ok then call next.
+	ctx.Next()
+}
+
+// OnForbidden is the default error handler for authorization.
+func OnForbidden(ctx iris.Context, err error) {
+	if err == nil {
+		return
+	}
+
+	WriteProblem(ctx, iris.StatusForbidden, ErrorCodeForbidden, err.Error())
+}
@@ -64,9 +64,7 @@ func OnError(ctx iris.Context, err error) {
 		return
 	}
 
-	ctx.StopExecution()
-	ctx.StatusCode(iris.StatusUnauthorized)
-	ctx.WriteString(err.Error())
+	WriteProblem(ctx, iris.StatusUnauthorized, ErrorCodeUnauthorized, err.Error())
 }
 
 // FromAuthHeader is a "TokenExtractor" that takes a give context and extracts
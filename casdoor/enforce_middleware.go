@@ -0,0 +1,261 @@
+package casdoor
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/abmpio/abmp/pkg/log"
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/kataras/iris/v12"
+)
+
+// IEnforcer decides whether the subject can do the action on the object
+type IEnforcer interface {
+	Enforce(subject string, object string, action string) (bool, error)
+}
+
+// CasdoorEnforcer calls casdoor's enforce api,
+// only one of PermissionId,ModelId,ResourceId,EnforcerId and Owner need to be set
+type CasdoorEnforcer struct {
+	PermissionId string
+	ModelId      string
+	ResourceId   string
+	EnforcerId   string
+	Owner        string
+}
+
+var _ IEnforcer = (*CasdoorEnforcer)(nil)
+
+func (e *CasdoorEnforcer) Enforce(subject string, object string, action string) (bool, error) {
+	return casdoorsdk.Enforce(e.PermissionId, e.ModelId, e.ResourceId, e.EnforcerId, e.Owner,
+		casdoorsdk.CasbinRequest{subject, object, action})
+}
+
+// MemoryEnforcer is an in-memory IEnforcer,use it to replace CasdoorEnforcer in tests.
+// "*" in a policy matches any value
+type MemoryEnforcer struct {
+	mu       sync.RWMutex
+	policies map[enforceRequest]struct{}
+}
+
+type enforceRequest struct {
+	subject string
+	object  string
+	action  string
+}
+
+var _ IEnforcer = (*MemoryEnforcer)(nil)
+
+func NewMemoryEnforcer() *MemoryEnforcer {
+	return &MemoryEnforcer{
+		policies: make(map[enforceRequest]struct{}),
+	}
+}
+
+func (e *MemoryEnforcer) AddPolicy(subject string, object string, action string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.policies[enforceRequest{subject: subject, object: object, action: action}] = struct{}{}
+}
+
+func (e *MemoryEnforcer) RemovePolicy(subject string, object string, action string) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	delete(e.policies, enforceRequest{subject: subject, object: object, action: action})
+}
+
+func (e *MemoryEnforcer) Enforce(subject string, object string, action string) (bool, error) {
+	e.mu.RLock()
+	defer e.mu.RUnlock()
+	for eachPolicy := range e.policies {
+		if matchPolicyValue(eachPolicy.subject, subject) &&
+			matchPolicyValue(eachPolicy.object, object) &&
+			matchPolicyValue(eachPolicy.action, action) {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func matchPolicyValue(policyValue string, v string) bool {
+	return policyValue == "*" || policyValue == v
+}
+
+const defaultEnforceCacheSize = 10000
+
+type EnforceOptions struct {
+	Enforcer IEnforcer
+	// how long a decision is cached,<=0 means no cache
+	CacheTTL time.Duration
+	// max number of cached decisions
+	// Default value: 10000
+	CacheSize int
+	// get the claims of current user
+	ClaimsGetter func(iris.Context) *casdoorsdk.Claims
+	// get casbin subject from claims
+	// Default value: owner/name of user
+	SubjectGetter func(*casdoorsdk.Claims) string
+	// get casbin object from request,it is also the key of cached decisions
+	// Default value: DefaultEnforceObject
+	ObjectGetter func(iris.Context) string
+	// The function that will be called when the request is denied
+	// Default value: OnForbidden
+	ErrorHandler errorHandler
+}
+
+// EnforceMiddleware checks (subject,route pattern,http method) with the enforcer
+type EnforceMiddleware struct {
+	Options EnforceOptions
+
+	cache *enforceDecisionCache
+}
+
+func NewEnforceMiddleware(options EnforceOptions) *EnforceMiddleware {
+	if options.SubjectGetter == nil {
+		options.SubjectGetter = DefaultEnforceSubject
+	}
+	if options.ObjectGetter == nil {
+		options.ObjectGetter = DefaultEnforceObject
+	}
+	if options.ErrorHandler == nil {
+		options.ErrorHandler = OnForbidden
+	}
+	if options.CacheSize <= 0 {
+		options.CacheSize = defaultEnforceCacheSize
+	}
+	m := &EnforceMiddleware{
+		Options: options,
+	}
+	if options.CacheTTL > 0 {
+		m.cache = newEnforceDecisionCache(options.CacheTTL, options.CacheSize)
+	}
+	return m
+}
+
+// owner/name of user
+func DefaultEnforceSubject(claims *casdoorsdk.Claims) string {
+	return fmt.Sprintf("%s/%s", claims.Owner, claims.Name)
+}
+
+// route pattern of request,such as /users/{id},so the requests of a route share one cached decision,
+// the request path is used if the route is unknown
+func DefaultEnforceObject(ctx iris.Context) string {
+	if route := ctx.GetCurrentRoute(); route != nil {
+		return route.Path()
+	}
+	return ctx.Path()
+}
+
+// Serve the middleware's action
+func (m *EnforceMiddleware) Serve(ctx iris.Context) {
+	var claims *casdoorsdk.Claims
+	if m.Options.ClaimsGetter != nil {
+		claims = m.Options.ClaimsGetter(ctx)
+	}
+	if claims == nil {
+		OnError(ctx, ErrTokenMissing)
+		return
+	}
+	allowed, err := m.Enforce(m.Options.SubjectGetter(claims), m.Options.ObjectGetter(ctx), ctx.Method())
+	if err != nil {
+		// the error of enforcer may expose the internal details,so it is only logged
+		log.Logger.Warn(fmt.Sprintf("Error enforcing request: %v", err))
+		WriteProblem(ctx, iris.StatusInternalServerError, ErrorCodeInternal, "failed to check the permission")
+		return
+	}
+	if !allowed {
+		m.Options.ErrorHandler(ctx, ErrForbidden)
+		return
+	}
+
+	// If everything ok then call next.
+	ctx.Next()
+}
+
+// enforce with the cached decision if exist
+func (m *EnforceMiddleware) Enforce(subject string, object string, action string) (bool, error) {
+	key := enforceRequest{subject: subject, object: object, action: action}
+	if m.cache != nil {
+		if allowed, ok := m.cache.get(key); ok {
+			return allowed, nil
+		}
+	}
+	allowed, err := m.Options.Enforcer.Enforce(subject, object, action)
+	if err != nil {
+		return false, err
+	}
+	if m.cache != nil {
+		m.cache.set(key, allowed)
+	}
+	return allowed, nil
+}
+
+// clear all cached decisions,call it after the policies changed
+func (m *EnforceMiddleware) ClearCache() {
+	if m.cache != nil {
+		m.cache.clear()
+	}
+}
+
+type enforceDecision struct {
+	allowed  bool
+	expireAt time.Time
+}
+
+type enforceDecisionCache struct {
+	ttl  time.Duration
+	size int
+
+	mu    sync.Mutex
+	items map[enforceRequest]enforceDecision
+}
+
+func newEnforceDecisionCache(ttl time.Duration, size int) *enforceDecisionCache {
+	return &enforceDecisionCache{
+		ttl:   ttl,
+		size:  size,
+		items: make(map[enforceRequest]enforceDecision),
+	}
+}
+
+func (c *enforceDecisionCache) get(key enforceRequest) (bool, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	decision, ok := c.items[key]
+	if !ok {
+		return false, false
+	}
+	if time.Now().After(decision.expireAt) {
+		delete(c.items, key)
+		return false, false
+	}
+	return decision.allowed, true
+}
+
+func (c *enforceDecisionCache) set(key enforceRequest, allowed bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	if len(c.items) >= c.size {
+		// remove expired decisions first,then all if still full
+		for eachKey, eachDecision := range c.items {
+			if now.After(eachDecision.expireAt) {
+				delete(c.items, eachKey)
+			}
+		}
+		if len(c.items) >= c.size {
+			c.items = make(map[enforceRequest]enforceDecision)
+		}
+	}
+	c.items[key] = enforceDecision{
+		allowed:  allowed,
+		expireAt: now.Add(c.ttl),
+	}
+}
+
+func (c *enforceDecisionCache) clear() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.items = make(map[enforceRequest]enforceDecision)
+}
@@ -0,0 +1,119 @@
+package casdoor
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+
+	"github.com/kataras/iris/v12"
+)
+
+// header used to pass the request id
+const RequestIdHeaderName = "X-Request-Id"
+
+// stable machine-readable error codes,written to the "code" member of problem details
+const (
+	ErrorCodeBadRequest         = "bad_request"
+	ErrorCodeValidationFailed   = "validation_failed"
+	ErrorCodeUnauthorized       = "unauthorized"
+	ErrorCodeForbidden          = "forbidden"
+	ErrorCodeNotFound           = "not_found"
+	ErrorCodeConflict           = "conflict"
+	ErrorCodePreconditionFailed = "precondition_failed"
+	ErrorCodeInternal           = "internal_error"
+)
+
+// FieldError is the field level detail of a validation failure
+type FieldError struct {
+	// json name of the field
+	Field string `json:"field"`
+	// the failed rule,such as required,not_allowed
+	Code    string `json:"code"`
+	Message string `json:"message,omitempty"`
+}
+
+// the default error code of http status code
+func DefaultErrorCode(statusCode int) string {
+	switch statusCode {
+	case iris.StatusBadRequest:
+		return ErrorCodeBadRequest
+	case iris.StatusUnauthorized:
+		return ErrorCodeUnauthorized
+	case iris.StatusForbidden:
+		return ErrorCodeForbidden
+	case iris.StatusNotFound:
+		return ErrorCodeNotFound
+	case iris.StatusConflict:
+		return ErrorCodeConflict
+	case iris.StatusPreconditionFailed:
+		return ErrorCodePreconditionFailed
+	}
+	if statusCode >= 400 && statusCode < 500 {
+		return ErrorCodeBadRequest
+	}
+	return ErrorCodeInternal
+}
+
+// WriteProblem stops the handlers chain and writes an application/problem+json(RFC 7807) response,
+// with the extension members code,requestId and errors
+func WriteProblem(ctx iris.Context, statusCode int, code string, detail string, fieldErrors ...FieldError) {
+	if len(code) <= 0 {
+		code = DefaultErrorCode(statusCode)
+	}
+	problem := iris.NewProblem().
+		Status(statusCode).
+		Title(iris.StatusText(statusCode)).
+		Key("code", code).
+		Key("requestId", GetRequestId(ctx))
+	if len(detail) > 0 {
+		problem.Detail(detail)
+	}
+	if len(fieldErrors) > 0 {
+		problem.Key("errors", fieldErrors)
+	}
+
+	ctx.StopExecution()
+	ctx.Problem(problem)
+}
+
+// GetRequestId returns the id of current request,
+// from X-Request-Id header or the id set by other middleware,
+// a new id is generated if not exist or the id of client is invalid
+func GetRequestId(ctx iris.Context) string {
+	requestId := ctx.GetHeader(RequestIdHeaderName)
+	if !isValidRequestId(requestId) {
+		requestId, _ = ctx.GetID().(string)
+	}
+	if !isValidRequestId(requestId) {
+		requestId = newRequestId()
+	}
+	ctx.SetID(requestId)
+	ctx.Header(RequestIdHeaderName, requestId)
+	return requestId
+}
+
+// max length of the request id of client
+const maxRequestIdLength = 128
+
+// the request id is written to headers,responses and logs,
+// so only letters,digits and -_.: are allowed
+func isValidRequestId(requestId string) bool {
+	if len(requestId) <= 0 || len(requestId) > maxRequestIdLength {
+		return false
+	}
+	for i := 0; i < len(requestId); i++ {
+		ch := requestId[i]
+		isLetterOrDigit := (ch >= 'a' && ch <= 'z') || (ch >= 'A' && ch <= 'Z') || (ch >= '0' && ch <= '9')
+		if !isLetterOrDigit && ch != '-' && ch != '_' && ch != '.' && ch != ':' {
+			return false
+		}
+	}
+	return true
+}
+
+func newRequestId() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
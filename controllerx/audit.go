@@ -0,0 +1,111 @@
+package controllerx
+
+import (
+	"context"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// AuditAction is the kind of change recorded by audit
+type AuditAction string
+
+const (
+	AuditActionCreate  AuditAction = "create"
+	AuditActionUpdate  AuditAction = "update"
+	AuditActionDelete  AuditAction = "delete"
+	AuditActionRestore AuditAction = "restore"
+
+	// collection of audit records used by default,in the database of entity collection
+	DefaultAuditCollectionName = "auditLogs"
+)
+
+// AuditFieldChange is the change of a field
+type AuditFieldChange struct {
+	// bson field of entity
+	Field string      `json:"field" bson:"field"`
+	Old   interface{} `json:"old,omitempty" bson:"old,omitempty"`
+	New   interface{} `json:"new,omitempty" bson:"new,omitempty"`
+}
+
+// AuditRecord is a change of an entity item
+type AuditRecord struct {
+	Id primitive.ObjectID `json:"id" bson:"_id,omitempty"`
+	// the name of entity
+	Entity   string      `json:"entity" bson:"entity"`
+	EntityId string      `json:"entityId" bson:"entityId"`
+	Action   AuditAction `json:"action" bson:"action"`
+	UserId   string      `json:"userId,omitempty" bson:"userId,omitempty"`
+	ClientIp string      `json:"clientIp,omitempty" bson:"clientIp,omitempty"`
+	Time     time.Time   `json:"time" bson:"time"`
+	// the changed fields,the old values are not known by update many
+	// and the fields are not known by delete list
+	Changes []AuditFieldChange `json:"changes,omitempty" bson:"changes,omitempty"`
+}
+
+// IAuditSink saves and queries the audit records
+type IAuditSink interface {
+	Write(ctx context.Context, records []*AuditRecord) error
+	// the records of an entity item,the newest first
+	FindHistory(ctx context.Context, entity string, entityId string, page int64, size int64) ([]*AuditRecord, int64, error)
+}
+
+// MongoAuditSink saves the audit records to mongo collection
+type MongoAuditSink struct {
+	Collection *mongo.Collection
+}
+
+var _ IAuditSink = (*MongoAuditSink)(nil)
+
+func NewMongoAuditSink(collection *mongo.Collection) *MongoAuditSink {
+	return &MongoAuditSink{
+		Collection: collection,
+	}
+}
+
+// create the index used by FindHistory
+func (s *MongoAuditSink) EnsureIndexes(ctx context.Context) error {
+	_, err := s.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys: bson.D{{Key: "entity", Value: 1}, {Key: "entityId", Value: 1}, {Key: "time", Value: -1}},
+	})
+	return err
+}
+
+func (s *MongoAuditSink) Write(ctx context.Context, records []*AuditRecord) error {
+	if len(records) <= 0 {
+		return nil
+	}
+	documents := make([]interface{}, 0, len(records))
+	for _, eachRecord := range records {
+		documents = append(documents, eachRecord)
+	}
+	_, err := s.Collection.InsertMany(ctx, documents)
+	return err
+}
+
+func (s *MongoAuditSink) FindHistory(ctx context.Context, entity string, entityId string, page int64, size int64) ([]*AuditRecord, int64, error) {
+	filter := bson.M{
+		"entity":   entity,
+		"entityId": entityId,
+	}
+	total, err := s.Collection.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, 0, err
+	}
+	findOptions := options.Find().
+		SetSort(bson.D{{Key: "time", Value: -1}, {Key: "_id", Value: -1}}).
+		SetSkip((page - 1) * size).
+		SetLimit(size)
+	cursor, err := s.Collection.Find(ctx, filter, findOptions)
+	if err != nil {
+		return nil, 0, err
+	}
+	records := make([]*AuditRecord, 0)
+	if err = cursor.All(ctx, &records); err != nil {
+		return nil, 0, err
+	}
+	return records, total, nil
+}
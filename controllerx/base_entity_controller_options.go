@@ -1,6 +1,12 @@
 package controllerx
 
-import "github.com/kataras/iris/v12"
+import (
+	"time"
+
+	"github.com/abmpio/irisx/casdoor"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/mongo"
+)
 
 type BaseControllerOptions struct {
 	RouterPath            string
@@ -23,6 +29,31 @@ func BaseControllerWithAuthenticatedDisabled(authenticatedDisabled bool) BaseCon
 	}
 }
 
+// endpoint name of EntityController
+type EntityEndpoint string
+
+const (
+	EntityEndpointAll        EntityEndpoint = "All"
+	EntityEndpointGetList    EntityEndpoint = "GetList"
+	EntityEndpointSearch     EntityEndpoint = "Search"
+	EntityEndpointGetById    EntityEndpoint = "GetById"
+	EntityEndpointCreate     EntityEndpoint = "Create"
+	EntityEndpointUpdate     EntityEndpoint = "Update"
+	EntityEndpointDelete     EntityEndpoint = "Delete"
+	EntityEndpointDeleteList EntityEndpoint = "DeleteList"
+	EntityEndpointRestore    EntityEndpoint = "Restore"
+	EntityEndpointAggregate  EntityEndpoint = "Aggregate"
+	EntityEndpointExport     EntityEndpoint = "Export"
+	EntityEndpointImport     EntityEndpoint = "Import"
+	// authorized as Create for POST and Update for PUT
+	EntityEndpointBatch EntityEndpoint = "Batch"
+	// authorized as both Create and Update
+	EntityEndpointUpsert     EntityEndpoint = "Upsert"
+	EntityEndpointUpdateMany EntityEndpoint = "UpdateMany"
+	// authorized as GetById
+	EntityEndpointHistory EntityEndpoint = "History"
+)
+
 type BaseEntityControllerOptions struct {
 	AllDisabled        bool
 	ListDisabled       bool
@@ -30,10 +61,83 @@ type BaseEntityControllerOptions struct {
 	GetByIdDisabled    bool
 	CreateDisabled     bool
 	UpdateDisabled     bool
+	PatchDisabled      bool
 	DeleteDisabled     bool
 	DeleteListDisabled bool
 
 	ListFilterFunc func(entityType interface{}, filter map[string]interface{}, ctx iris.Context)
+	// hooks called around crud operations,in registration order
+	Hooks []IEntityControllerHook
+	// only the items created by current user can be read and changed,
+	// takes effect when entity implements entity.IEntityWithUser
+	OwnerScoped bool
+	// delete by setting isDeleted,deletionTime and deleterId instead of removing the document,
+	// the deleted items are excluded from reads unless includeDeleted is true
+	SoftDeleteEnabled bool
+	// the isDeleted condition of client filter replaces the exclusion of deleted items,
+	// otherwise the deleted items are only returned by includeDeleted
+	SoftDeleteFilterAllowed bool
+	// GetById returns ETag and Update checks If-Match header
+	ConcurrencyControlEnabled bool
+	// the version field incremented by each update,
+	// lastModificationTime is used as concurrency token if it is empty,T must implement mongodbr.IModificationEntity then
+	VersionField string
+	// PUT and PATCH without If-Match header are rejected with 428 when concurrency control is enabled
+	IfMatchRequired bool
+	// the roles,groups and permissions required by each endpoint
+	Authorizations map[EntityEndpoint]casdoor.Authorization
+	// the fields can be set by create,all fields except the protected are allowed if it is empty
+	CreatableFields []string
+	// the fields can be set by update,all fields except the protected are allowed if it is empty
+	UpdatableFields []string
+
+	// the fields can be filtered by search and their allowed operators,
+	// all of the operators are allowed if the list is empty,
+	// all of the fields except the hidden are allowed if it is empty,otherwise the fields not in it can not be filtered
+	FilterableFields map[string][]FilterOperator
+	// the fields can be sorted by,the hidden fields are never sortable,
+	// only _id is sortable in cursor mode and all fields are sortable in the other modes if it is empty
+	SortableFields []string
+	// the fields never returned by read endpoints,such as password hashes
+	HiddenFields []string
+	// register POST /aggregate endpoint
+	AggregateEnabled bool
+	// the fields can be grouped by and aggregated by metrics
+	AggregatableFields []string
+	// register GET /export and POST /export endpoints
+	ExportEnabled bool
+	// the columns of exported file,the json fields of entity and their export tags are used if it is empty
+	ExportColumns []ExportColumn
+	// file name of exported file without extension,export by default
+	ExportFileName string
+	// register POST /import endpoint
+	ImportEnabled bool
+	// register POST /batch and PUT /batch endpoints
+	BatchEnabled bool
+	// the fields identifying an item by PUT /upsert,the endpoint is registered if it is not empty
+	UpsertKeys []string
+	// register PATCH / endpoint which updates the items matching a filter
+	UpdateManyEnabled bool
+	// replay the response of Create,batch,upsert and update many requests retried with the same Idempotency-Key,
+	// disabled if it is nil
+	IdempotencyStore IIdempotencyStore
+	// how long the response of an idempotency key is kept,DefaultIdempotencyTTL is used if it is not positive
+	IdempotencyTTL time.Duration
+	// record the changes of create,update,delete and restore and register GET /{id}/history endpoint
+	AuditEnabled bool
+	// the sink of audit records,the auditLogs collection in the database of entity collection is used if it is nil
+	AuditSink IAuditSink
+	// the name of entity in audit records,the type name of entity is used if it is empty
+	AuditEntityName string
+	// the relations can be expanded by read endpoints
+	Relations []IEntityRelation
+	// default and max page size of list,search and all
+	PaginationPolicy PaginationPolicy
+	// parse the {id} route parameter and the ids of DeleteList payload,ObjectIdCodec is used if it is nil
+	IdCodec IIdCodec
+
+	// get the mongo collection of entity
+	CollectionFunc func() *mongo.Collection
 
 	BaseControllerOptions
 }
@@ -48,6 +152,7 @@ func BaseEntityControllerWithAllEndpointDisabled(v bool) BaseEntityControllerOpt
 		rro.GetByIdDisabled = v
 		rro.CreateDisabled = v
 		rro.UpdateDisabled = v
+		rro.PatchDisabled = v
 		rro.DeleteDisabled = v
 		rro.DeleteListDisabled = v
 	}
@@ -101,6 +206,12 @@ func BaseEntityControllerWithUpdateDisabled(v bool) BaseEntityControllerOption {
 	}
 }
 
+func BaseEntityControllerWithPatchDisabled(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.PatchDisabled = v
+	}
+}
+
 func BaseEntityControllerWithDeleteDisabled(v bool) BaseEntityControllerOption {
 	return func(beco *BaseEntityControllerOptions) {
 		beco.DeleteDisabled = v
@@ -112,3 +223,204 @@ func BaseEntityControllerWithDeleteListDisabled(v bool) BaseEntityControllerOpti
 		beco.DeleteListDisabled = v
 	}
 }
+
+// add hooks which are called around crud operations
+func BaseEntityControllerWithHooks(hooks ...IEntityControllerHook) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.Hooks = append(beco.Hooks, hooks...)
+	}
+}
+
+// enable owner scoped mode,every read is filtered by creatorId and every mutation checks the ownership
+func BaseEntityControllerWithOwnerScoped(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.OwnerScoped = v
+	}
+}
+
+// set the authorization required by endpoint
+func BaseEntityControllerWithAuthorization(endpoint EntityEndpoint, authorization casdoor.Authorization) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		if beco.Authorizations == nil {
+			beco.Authorizations = make(map[EntityEndpoint]casdoor.Authorization)
+		}
+		beco.Authorizations[endpoint] = authorization
+	}
+}
+
+// set the permissions required by endpoint,user must have any of them
+func BaseEntityControllerWithPermissions(endpoint EntityEndpoint, permissions ...string) BaseEntityControllerOption {
+	return BaseEntityControllerWithAuthorization(endpoint, casdoor.Authorization{
+		Permissions: permissions,
+	})
+}
+
+// set the roles required by endpoint,user must have any of them
+func BaseEntityControllerWithRoles(endpoint EntityEndpoint, roles ...string) BaseEntityControllerOption {
+	return BaseEntityControllerWithAuthorization(endpoint, casdoor.Authorization{
+		Roles: roles,
+	})
+}
+
+// enable soft delete,also register POST /{id}/restore endpoint
+func BaseEntityControllerWithSoftDelete(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.SoftDeleteEnabled = v
+	}
+}
+
+// allow the client filter on isDeleted to replace the exclusion of deleted items
+func BaseEntityControllerWithSoftDeleteFilter(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.SoftDeleteFilterAllowed = v
+	}
+}
+
+// enable optimistic concurrency control,
+// versionField is incremented by each update,lastModificationTime is used if it is empty
+func BaseEntityControllerWithConcurrencyControl(versionField string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.ConcurrencyControlEnabled = true
+		beco.VersionField = versionField
+	}
+}
+
+// reject PUT and PATCH without If-Match header with 428
+func BaseEntityControllerWithIfMatchRequired(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.IfMatchRequired = v
+	}
+}
+
+// set the function to get the mongo collection of entity
+func BaseEntityControllerWithCollection(collectionFunc func() *mongo.Collection) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.CollectionFunc = collectionFunc
+	}
+}
+
+// allow field to be filtered by search with the operators,all of the operators are allowed if it is empty
+func BaseEntityControllerWithFilterableField(field string, operators ...FilterOperator) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		if beco.FilterableFields == nil {
+			beco.FilterableFields = make(map[string][]FilterOperator)
+		}
+		beco.FilterableFields[field] = operators
+	}
+}
+
+// allow the fields to be sorted by in cursor mode
+func BaseEntityControllerWithSortableFields(fields ...string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.SortableFields = append(beco.SortableFields, fields...)
+	}
+}
+
+// set the fields never returned by read endpoints
+func BaseEntityControllerWithHiddenFields(fields ...string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.HiddenFields = append(beco.HiddenFields, fields...)
+	}
+}
+
+// add relations can be expanded by ?expand=
+func BaseEntityControllerWithRelations(relations ...IEntityRelation) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.Relations = append(beco.Relations, relations...)
+	}
+}
+
+// enable POST /aggregate endpoint,fields can be grouped by and aggregated by metrics
+func BaseEntityControllerWithAggregate(fields ...string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.AggregateEnabled = true
+		beco.AggregatableFields = append(beco.AggregatableFields, fields...)
+	}
+}
+
+// enable GET /export and POST /export endpoints,columns are the json fields of entity if it is empty
+func BaseEntityControllerWithExport(columns ...ExportColumn) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.ExportEnabled = true
+		beco.ExportColumns = append(beco.ExportColumns, columns...)
+	}
+}
+
+// enable POST /import endpoint
+func BaseEntityControllerWithImport(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.ImportEnabled = v
+	}
+}
+
+// enable POST /batch and PUT /batch endpoints
+func BaseEntityControllerWithBatch(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.BatchEnabled = v
+	}
+}
+
+// enable PUT /upsert endpoint,the items are matched by the key fields
+func BaseEntityControllerWithUpsert(keys ...string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.UpsertKeys = append(beco.UpsertKeys, keys...)
+	}
+}
+
+// enable PATCH / endpoint
+func BaseEntityControllerWithUpdateMany(v bool) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.UpdateManyEnabled = v
+	}
+}
+
+// enable Idempotency-Key header of Create,batch,upsert and update many endpoints
+func BaseEntityControllerWithIdempotency(store IIdempotencyStore, ttl time.Duration) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.IdempotencyStore = store
+		beco.IdempotencyTTL = ttl
+	}
+}
+
+// enable audit,the default mongo sink is used if sink is nil
+func BaseEntityControllerWithAudit(sink IAuditSink) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.AuditEnabled = true
+		beco.AuditSink = sink
+	}
+}
+
+// set the pagination policy
+func BaseEntityControllerWithPaginationPolicy(policy PaginationPolicy) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.PaginationPolicy = policy
+	}
+}
+
+// set the max page size,the oversized request is clamped
+func BaseEntityControllerWithMaxPageSize(maxSize int) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.PaginationPolicy.MaxSize = maxSize
+	}
+}
+
+// set the codec of entity id
+func BaseEntityControllerWithIdCodec(codec IIdCodec) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.IdCodec = codec
+	}
+}
+
+// set the fields can be set by create
+func BaseEntityControllerWithCreatableFields(fields ...string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.CreatableFields = fields
+	}
+}
+
+// set the fields can be set by update
+func BaseEntityControllerWithUpdatableFields(fields ...string) BaseEntityControllerOption {
+	return func(beco *BaseEntityControllerOptions) {
+		beco.UpdatableFields = fields
+	}
+}
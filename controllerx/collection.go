@@ -0,0 +1,33 @@
+package controllerx
+
+import (
+	"errors"
+
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// ICollectionProvider is implemented by the entity services which expose their mongo collection
+type ICollectionProvider interface {
+	GetCollection() *mongo.Collection
+}
+
+var ErrCollectionNotAvailable = errors.New("mongo collection is not available,set it by BaseEntityControllerWithCollection or implement ICollectionProvider in entity service")
+
+// get the mongo collection of entity,
+// used by the operations which are not supported by entity.IEntityService
+func (c *EntityController[T]) GetCollection() (*mongo.Collection, error) {
+	if c.Options.CollectionFunc != nil {
+		collection := c.Options.CollectionFunc()
+		if collection != nil {
+			return collection, nil
+		}
+	}
+	provider, ok := c.GetEntityService().(ICollectionProvider)
+	if ok {
+		collection := provider.GetCollection()
+		if collection != nil {
+			return collection, nil
+		}
+	}
+	return nil, ErrCollectionNotAvailable
+}
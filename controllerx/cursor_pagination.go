@@ -0,0 +1,387 @@
+package controllerx
+
+import (
+	"encoding/base64"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/abmpio/entity"
+	"github.com/abmpio/mongodbr"
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// query parameter or search input field of cursor,cursor mode is used when it is present
+	CursorParamName = "cursor"
+	// query parameter or search input field to count the total in cursor mode
+	IncludeTotalParamName = "includeTotal"
+)
+
+// CursorListData is the response data of cursor mode
+type CursorListData struct {
+	Items interface{} `json:"items"`
+	// cursor of the next page,empty if there is no more items
+	NextCursor string `json:"nextCursor,omitempty"`
+	// cursor of the previous page,empty if it is the first page
+	PrevCursor string `json:"prevCursor,omitempty"`
+	// only returned when includeTotal is true
+	Total *int64 `json:"total,omitempty"`
+}
+
+// CursorSort is a sort key of cursor mode
+type CursorSort struct {
+	Key string
+	Asc bool
+}
+
+// pageCursor is the decoded cursor,the sort key values of the boundary item
+type pageCursor struct {
+	Keys   []string `bson:"k"`
+	Values bson.A   `bson:"v"`
+	// the cursor is used to get the previous page
+	Prev bool `bson:"p"`
+}
+
+// encode cursor as base64 of extended json,so the value types are kept
+func encodeCursor(cursor *pageCursor) (string, error) {
+	data, err := bson.MarshalExtJSON(cursor, true, false)
+	if err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(data), nil
+}
+
+func decodeCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, NewHttpErrorf(iris.StatusBadRequest, "invalid cursor")
+	}
+	cursor := &pageCursor{}
+	if err = bson.UnmarshalExtJSON(data, true, cursor); err != nil {
+		return nil, NewHttpErrorf(iris.StatusBadRequest, "invalid cursor")
+	}
+	return cursor, nil
+}
+
+// the cursor must be made for the sort keys,
+// the values are used in the keyset filter,so they must not contain mongo operators
+func checkPageCursor(cursor *pageCursor, keys []string) error {
+	if !reflect.DeepEqual(cursor.Keys, keys) || len(cursor.Values) != len(keys) {
+		return NewHttpErrorf(iris.StatusBadRequest, "cursor does not match the sort,sort keys:%s", strings.Join(keys, ","))
+	}
+	if err := checkFilterValue([]interface{}(cursor.Values)); err != nil {
+		return NewHttpErrorf(iris.StatusBadRequest, "invalid cursor,%s", err.Error())
+	}
+	return nil
+}
+
+// check the sorts and map their keys to the bson names of T,
+// the hidden fields can not be sorted by,or their values could be guessed by the order,
+// the sort keys of cursor mode are used in the keyset filter,so they must be sortable,
+// the other modes only check the sortable fields when they are configured
+func (c *EntityController[T]) checkSorts(sorts []CursorSort, cursorMode bool) ([]CursorSort, error) {
+	checked := make([]CursorSort, 0, len(sorts))
+	seen := make(map[string]struct{}, len(sorts))
+	for _, eachSort := range sorts {
+		if eachSort.Key != "_id" {
+			if !isSafeFilterField(eachSort.Key) || c.isHiddenField(eachSort.Key) {
+				return nil, NewHttpErrorf(iris.StatusBadRequest, "field %s can not be sorted by", eachSort.Key)
+			}
+			if (cursorMode || len(c.Options.SortableFields) > 0) && !c.isSortableField(eachSort.Key) {
+				return nil, NewHttpErrorf(iris.StatusBadRequest, "field %s can not be sorted by", eachSort.Key)
+			}
+		}
+		// the json name of id is mapped to _id which may be the tie breaker too
+		key := c.bsonFieldName(eachSort.Key)
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		checked = append(checked, CursorSort{Key: key, Asc: eachSort.Asc})
+	}
+	return checked, nil
+}
+
+// whether the json or bson name of field is in the sortable fields,the hidden fields are not sortable
+func (c *EntityController[T]) isSortableField(field string) bool {
+	if c.isHiddenField(field) {
+		return false
+	}
+	names := []string{field}
+	if entityField := GetEntityFields(new(T)).Get(field); entityField != nil {
+		names = append(names, entityField.Names()...)
+	}
+	for _, eachField := range c.Options.SortableFields {
+		for _, eachName := range names {
+			if eachField == eachName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// the sort options of mongodbr
+func fieldSortOptions(sorts []CursorSort) []mongodbr.MongodbrFindOption {
+	findOptions := make([]mongodbr.MongodbrFindOption, 0, len(sorts)+1)
+	for _, eachSort := range sorts {
+		findOptions = append(findOptions, mongodbr.MongodbrFindOptionWithFieldSort(eachSort.Key, eachSort.Asc))
+	}
+	return findOptions
+}
+
+// the sort document of mongo
+func mongoSort(sorts []CursorSort) bson.D {
+	sort := make(bson.D, 0, len(sorts))
+	for _, eachSort := range sorts {
+		direction := 1
+		if !eachSort.Asc {
+			direction = -1
+		}
+		sort = append(sort, bson.E{Key: eachSort.Key, Value: direction})
+	}
+	return sort
+}
+
+// get cursor sorts from SortInput,_id is appended as the tie breaker
+func CursorSortsFromSortInput(i SortInput) []CursorSort {
+	return withIdCursorSort(sortsFromSortInput(i))
+}
+
+func sortsFromSortInput(i SortInput) []CursorSort {
+	sorts := make([]CursorSort, 0, len(i.Sorts)+1)
+	for _, eachSort := range i.Sorts {
+		if len(eachSort.Key) <= 0 {
+			continue
+		}
+		switch eachSort.Direction {
+		case entity.ASCENDING, entity.ASC:
+			sorts = append(sorts, CursorSort{Key: eachSort.Key, Asc: true})
+		case entity.DESCENDING, entity.DESC:
+			sorts = append(sorts, CursorSort{Key: eachSort.Key, Asc: false})
+		}
+	}
+	return sorts
+}
+
+// get cursor sorts from the mongo sort document,_id is appended as the tie breaker
+func CursorSortsFromSortOption(sort interface{}) ([]CursorSort, error) {
+	sorts, err := sortsFromSortOption(sort)
+	if err != nil {
+		return nil, err
+	}
+	return withIdCursorSort(sorts), nil
+}
+
+// the sort document can be bson.D,bson.E or a map with at most one key,
+// the map with more keys is rejected because its order is undefined
+func sortsFromSortOption(sort interface{}) ([]CursorSort, error) {
+	sorts := make([]CursorSort, 0)
+	switch v := sort.(type) {
+	case nil:
+	case bson.D:
+		for _, eachElement := range v {
+			sorts = append(sorts, CursorSort{Key: eachElement.Key, Asc: isAscSortValue(eachElement.Value)})
+		}
+	case bson.E:
+		sorts = append(sorts, CursorSort{Key: v.Key, Asc: isAscSortValue(v.Value)})
+	case bson.M:
+		return sortsFromSortMap(v)
+	case map[string]interface{}:
+		return sortsFromSortMap(v)
+	default:
+		return nil, NewHttpErrorf(iris.StatusBadRequest, "unsupported sort:%v", sort)
+	}
+	return sorts, nil
+}
+
+func sortsFromSortMap(m map[string]interface{}) ([]CursorSort, error) {
+	if len(m) > 1 {
+		return nil, NewHttpErrorf(iris.StatusBadRequest, "sort by more than one field must be ordered")
+	}
+	sorts := make([]CursorSort, 0, len(m))
+	for key, value := range m {
+		sorts = append(sorts, CursorSort{Key: key, Asc: isAscSortValue(value)})
+	}
+	return sorts, nil
+}
+
+func isAscSortValue(v interface{}) bool {
+	value := reflect.ValueOf(v)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return value.Int() >= 0
+	case reflect.Float32, reflect.Float64:
+		return value.Float() >= 0
+	}
+	return true
+}
+
+func withIdCursorSort(sorts []CursorSort) []CursorSort {
+	for _, eachSort := range sorts {
+		if eachSort.Key == "_id" {
+			return sorts
+		}
+	}
+	asc := true
+	if len(sorts) > 0 {
+		asc = sorts[len(sorts)-1].Asc
+	}
+	return append(sorts, CursorSort{Key: "_id", Asc: asc})
+}
+
+// filter of the items after the cursor in the sort order,
+// for sorts a,b it is {$or:[{a:{$gt:va}},{a:va,b:{$gt:vb}}]}
+func keysetFilter(sorts []CursorSort, values bson.A, forward bool) bson.M {
+	conditions := make([]bson.M, 0, len(sorts))
+	for i, eachSort := range sorts {
+		condition := bson.M{}
+		for j := 0; j < i; j++ {
+			condition[sorts[j].Key] = values[j]
+		}
+		op := "$gt"
+		if eachSort.Asc != forward {
+			op = "$lt"
+		}
+		condition[eachSort.Key] = bson.M{op: values[i]}
+		conditions = append(conditions, condition)
+	}
+	return bson.M{"$or": conditions}
+}
+
+// values of the sort keys of item
+func cursorValues(item interface{}, sorts []CursorSort) (bson.A, error) {
+	m, err := entityToBsonM(item)
+	if err != nil {
+		return nil, err
+	}
+	values := make(bson.A, 0, len(sorts))
+	for _, eachSort := range sorts {
+		values = append(values, bsonMPathValue(m, eachSort.Key))
+	}
+	return values, nil
+}
+
+// value of the dotted path in m
+func bsonMPathValue(m bson.M, path string) interface{} {
+	var current interface{} = m
+	for _, eachPart := range strings.Split(path, ".") {
+		node, ok := current.(bson.M)
+		if !ok {
+			return nil
+		}
+		current = node[eachPart]
+	}
+	return current
+}
+
+func cursorSortKeys(sorts []CursorSort) []string {
+	keys := make([]string, 0, len(sorts))
+	for _, eachSort := range sorts {
+		keys = append(keys, eachSort.Key)
+	}
+	return keys
+}
+
+// find a page of items by cursor,an empty cursor value gets the first page
+func (c *EntityController[T]) findListByCursor(ctx iris.Context, query bson.M, sorts []CursorSort, cursorValue string, size int, includeTotal bool) (*CursorListData, []*T, error) {
+	if size <= 0 {
+		size = entity.PaginationDefaultSize
+	}
+	sorts, err := c.checkSorts(sorts, true)
+	if err != nil {
+		return nil, nil, err
+	}
+	keys := cursorSortKeys(sorts)
+	forward := true
+	filter := query
+	if len(cursorValue) > 0 {
+		cursor, err := decodeCursor(cursorValue)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err = checkPageCursor(cursor, keys); err != nil {
+			return nil, nil, err
+		}
+		forward = !cursor.Prev
+		filter = bson.M{"$and": bson.A{query, keysetFilter(sorts, cursor.Values, forward)}}
+	}
+
+	findSorts := make([]CursorSort, 0, len(sorts))
+	for _, eachSort := range sorts {
+		findSorts = append(findSorts, CursorSort{Key: eachSort.Key, Asc: eachSort.Asc == forward})
+	}
+	// one more item to know whether there are more items
+	list, err := c.findList(ctx, filter, findSorts, 1, int64(size+1))
+	if err != nil {
+		return nil, nil, err
+	}
+	hasMore := len(list) > size
+	if hasMore {
+		list = list[:size]
+	}
+	if !forward {
+		for i, j := 0, len(list)-1; i < j; i, j = i+1, j-1 {
+			list[i], list[j] = list[j], list[i]
+		}
+	}
+
+	data := &CursorListData{}
+	if len(list) > 0 {
+		// there are items after the last one when paging forward with more items or paging backward
+		if (forward && hasMore) || (!forward && len(cursorValue) > 0) {
+			data.NextCursor, err = c.boundaryCursor(list[len(list)-1], sorts, false)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+		if (!forward && hasMore) || (forward && len(cursorValue) > 0) {
+			data.PrevCursor, err = c.boundaryCursor(list[0], sorts, true)
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	if includeTotal {
+		total, err := c.GetEntityService().Count(query)
+		if err != nil {
+			return nil, nil, err
+		}
+		data.Total = &total
+	}
+	return data, list, nil
+}
+
+func (c *EntityController[T]) boundaryCursor(item *T, sorts []CursorSort, prev bool) (string, error) {
+	values, err := cursorValues(item, sorts)
+	if err != nil {
+		return "", err
+	}
+	cursor, err := encodeCursor(&pageCursor{
+		Keys:   cursorSortKeys(sorts),
+		Values: values,
+		Prev:   prev,
+	})
+	if err != nil {
+		return "", fmt.Errorf("encode cursor failed,%s", err.Error())
+	}
+	return cursor, nil
+}
+
+// response a page of cursor mode
+func (c *EntityController[T]) handleCursorList(ctx iris.Context, query bson.M, sorts []CursorSort, cursorValue string, size int, includeTotal bool, fields []string, expand []string) {
+	data, list, err := c.findListByCursor(ctx, query, sorts, cursorValue, size, includeTotal)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	data.Items, err = c.handleFindResult(ctx, list, fields, expand)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccessWithData(ctx, data)
+}
@@ -0,0 +1,187 @@
+package controllerx
+
+import (
+	"encoding/base64"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/abmpio/entity"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestEncodeCursor(t *testing.T) {
+	objectId := primitive.NewObjectID()
+	day := primitive.NewDateTimeFromTime(time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC))
+	tests := []struct {
+		name   string
+		cursor *pageCursor
+	}{
+		{"string and object id", &pageCursor{Keys: []string{"name", "_id"}, Values: bson.A{"a", objectId}}},
+		{"typed numbers", &pageCursor{Keys: []string{"age", "score", "_id"}, Values: bson.A{int32(1), int64(1) << 60, objectId}}},
+		{"time and prev", &pageCursor{Keys: []string{"creationTime", "_id"}, Values: bson.A{day, "x"}, Prev: true}},
+		{"null value", &pageCursor{Keys: []string{"name", "_id"}, Values: bson.A{nil, "x"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			value, err := encodeCursor(tt.cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decodeCursor(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(got, tt.cursor) {
+				t.Fatalf("got %#v,want %#v", got, tt.cursor)
+			}
+		})
+	}
+}
+
+func TestDecodeCursorInvalid(t *testing.T) {
+	for _, eachValue := range []string{"!", base64.RawURLEncoding.EncodeToString([]byte("not json"))} {
+		if _, err := decodeCursor(eachValue); err == nil {
+			t.Fatalf("expected error of cursor %s", eachValue)
+		}
+	}
+}
+
+func TestCheckPageCursor(t *testing.T) {
+	keys := []string{"name", "_id"}
+	tests := []struct {
+		name    string
+		cursor  *pageCursor
+		wantErr bool
+	}{
+		{"valid", &pageCursor{Keys: keys, Values: bson.A{"a", "1"}}, false},
+		{"other keys", &pageCursor{Keys: []string{"age", "_id"}, Values: bson.A{1, "1"}}, true},
+		{"values missing", &pageCursor{Keys: keys, Values: bson.A{"a"}}, true},
+		{"operator value", &pageCursor{Keys: keys, Values: bson.A{bson.D{{Key: "$ne", Value: nil}}, "1"}}, true},
+		{"nested operator value", &pageCursor{Keys: keys, Values: bson.A{bson.A{bson.M{"$gt": ""}}, "1"}}, true},
+		{"document value", &pageCursor{Keys: keys, Values: bson.A{bson.D{{Key: "a", Value: 1}}, "1"}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the values are decoded from the encoded cursor like the client sent it
+			value, err := encodeCursor(tt.cursor)
+			if err != nil {
+				t.Fatal(err)
+			}
+			cursor, err := decodeCursor(value)
+			if err != nil {
+				t.Fatal(err)
+			}
+			err = checkPageCursor(cursor, keys)
+			if tt.wantErr != (err != nil) {
+				t.Fatalf("wantErr %v,got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestKeysetFilter(t *testing.T) {
+	sorts := []CursorSort{{Key: "name", Asc: true}, {Key: "_id", Asc: false}}
+	values := bson.A{"a", "1"}
+	tests := []struct {
+		name    string
+		forward bool
+		want    bson.M
+	}{
+		{
+			"forward",
+			true,
+			bson.M{"$or": []bson.M{
+				{"name": bson.M{"$gt": "a"}},
+				{"name": "a", "_id": bson.M{"$lt": "1"}},
+			}},
+		},
+		{
+			"backward",
+			false,
+			bson.M{"$or": []bson.M{
+				{"name": bson.M{"$lt": "a"}},
+				{"name": "a", "_id": bson.M{"$gt": "1"}},
+			}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := keysetFilter(sorts, values, tt.forward)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorSortsFromSortOption(t *testing.T) {
+	tests := []struct {
+		name    string
+		sort    interface{}
+		want    []CursorSort
+		wantErr bool
+	}{
+		{"no sort", nil, []CursorSort{{Key: "_id", Asc: true}}, false},
+		{
+			"sort document",
+			bson.D{{Key: "name", Value: 1}, {Key: "age", Value: int64(-1)}},
+			[]CursorSort{{Key: "name", Asc: true}, {Key: "age", Asc: false}, {Key: "_id", Asc: false}},
+			false,
+		},
+		{
+			"sort document with _id",
+			bson.D{{Key: "_id", Value: -1}, {Key: "name", Value: 1}},
+			[]CursorSort{{Key: "_id", Asc: false}, {Key: "name", Asc: true}},
+			false,
+		},
+		{"single element", bson.E{Key: "name", Value: -1}, []CursorSort{{Key: "name", Asc: false}, {Key: "_id", Asc: false}}, false},
+		{"single key map", bson.M{"name": 1}, []CursorSort{{Key: "name", Asc: true}, {Key: "_id", Asc: true}}, false},
+		{"map with more keys", bson.M{"name": 1, "age": -1}, nil, true},
+		{"unsupported", "name", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CursorSortsFromSortOption(tt.sort)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("got error %v,want error %v", err, tt.wantErr)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCursorSortsFromSortInput(t *testing.T) {
+	got := CursorSortsFromSortInput(SortInput{Sorts: []entity.Sort{{Key: "name", Direction: entity.DESC}, {Key: ""}}})
+	if want := []CursorSort{{Key: "name", Asc: false}, {Key: "_id", Asc: false}}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v,want %v", got, want)
+	}
+}
+
+func TestBsonMPathValue(t *testing.T) {
+	m := bson.M{"a": bson.M{"b": 1}, "c": 2}
+	tests := []struct {
+		path string
+		want interface{}
+	}{
+		{"c", 2},
+		{"a.b", 1},
+		{"a.x", nil},
+		{"c.d", nil},
+	}
+	for _, tt := range tests {
+		if got := bsonMPathValue(m, tt.path); !reflect.DeepEqual(got, tt.want) {
+			t.Fatalf("path %s got %v,want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestMongoSort(t *testing.T) {
+	got := mongoSort([]CursorSort{{Key: "name", Asc: true}, {Key: "_id", Asc: false}})
+	if want := (bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: -1}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v,want %v", got, want)
+	}
+}
@@ -1,8 +1,7 @@
 package controllerx
 
 import (
-	"errors"
-	"fmt"
+	"encoding/json"
 	"sync"
 	"time"
 
@@ -14,7 +13,6 @@ import (
 	"github.com/kataras/iris/v12/context"
 	"github.com/kataras/iris/v12/core/router"
 	"go.mongodb.org/mongo-driver/bson"
-	"go.mongodb.org/mongo-driver/bson/primitive"
 
 	webapp "github.com/abmpio/webserver/app"
 )
@@ -46,38 +44,94 @@ func (c *EntityController[T]) RegistRouter(webapp *webapp.Application, opts ...B
 	for _, eachOpt := range opts {
 		eachOpt(&(c.Options))
 	}
+	// the misconfigured controller is rejected when the routes are registered
+	if err := c.checkConcurrencyOptions(); err != nil {
+		panic(err)
+	}
 
 	c.handlerList = defaultContextHandlers(&c.Options.BaseControllerOptions)
 	routerParty := webapp.Party(c.Options.RouterPath, c.handlerList...)
 
 	if !c.Options.AllDisabled {
-		routerParty.Get("/all", c.All)
+		routerParty.Get("/all", c.endpointHandlers(EntityEndpointAll, c.All)...)
 	}
 	if !c.Options.ListDisabled {
-		routerParty.Get("/", c.GetList)
+		routerParty.Get("/", c.endpointHandlers(EntityEndpointGetList, c.GetList)...)
 	}
 	if !c.Options.SearchDiabled {
-		routerParty.Post("/search", c.Search)
+		routerParty.Post("/search", c.endpointHandlers(EntityEndpointSearch, c.Search)...)
+	}
+	if c.Options.ExportEnabled {
+		routerParty.Get("/export", c.endpointHandlers(EntityEndpointExport, c.ExportByQuery)...)
+		routerParty.Post("/export", c.endpointHandlers(EntityEndpointExport, c.Export)...)
+	}
+	if c.Options.ImportEnabled {
+		routerParty.Post("/import", c.endpointHandlers(EntityEndpointImport, c.Import)...)
+	}
+	if c.Options.BatchEnabled {
+		routerParty.Post("/batch", c.endpointHandlers(EntityEndpointBatch, c.CreateBatch, EntityEndpointCreate)...)
+		routerParty.Put("/batch", c.endpointHandlers(EntityEndpointBatch, c.UpdateBatch, EntityEndpointUpdate)...)
+	}
+	if len(c.Options.UpsertKeys) > 0 {
+		// upsert may create or update each item,so both of the authorizations are required
+		routerParty.Put("/upsert", c.endpointHandlers(EntityEndpointUpsert, c.Upsert, EntityEndpointCreate, EntityEndpointUpdate)...)
+	}
+	if c.Options.AggregateEnabled {
+		routerParty.Post("/aggregate", c.endpointHandlers(EntityEndpointAggregate, c.Aggregate)...)
 	}
 	if !c.Options.GetByIdDisabled {
-		routerParty.Get("/{id}", c.GetById)
+		routerParty.Get("/{id}", c.endpointHandlers(EntityEndpointGetById, c.GetById)...)
 	}
 	if !c.Options.CreateDisabled {
-		routerParty.Post("/", c.Create)
+		routerParty.Post("/", c.endpointHandlers(EntityEndpointCreate, c.Create)...)
 	}
 	if !c.Options.UpdateDisabled {
-		routerParty.Put("/{id}", c.Update)
+		routerParty.Put("/{id}", c.endpointHandlers(EntityEndpointUpdate, c.Update)...)
+	}
+	if !c.Options.UpdateDisabled && !c.Options.PatchDisabled {
+		// patch is a partial update,so it is authorized as update
+		routerParty.Patch("/{id}", c.endpointHandlers(EntityEndpointUpdate, c.Patch)...)
 	}
 	if !c.Options.DeleteDisabled {
-		routerParty.Delete("/{id}", c.Delete)
+		routerParty.Delete("/{id}", c.endpointHandlers(EntityEndpointDelete, c.Delete)...)
+	}
+	if c.Options.UpdateManyEnabled {
+		routerParty.Patch("/", c.endpointHandlers(EntityEndpointUpdateMany, c.UpdateMany)...)
 	}
 	if !c.Options.DeleteListDisabled {
-		routerParty.Delete("/", c.DeleteList)
+		routerParty.Delete("/", c.endpointHandlers(EntityEndpointDeleteList, c.DeleteList)...)
+	}
+	if c.Options.SoftDeleteEnabled {
+		routerParty.Post("/{id}/restore", c.endpointHandlers(EntityEndpointRestore, c.Restore)...)
+	}
+	if c.Options.AuditEnabled {
+		// the history is a read of the item,so it is authorized as get by id
+		routerParty.Get("/{id}/history", c.endpointHandlers(EntityEndpointHistory, c.History, EntityEndpointGetById)...)
 	}
 
 	return routerParty
 }
 
+// handlers of endpoint,with the authorization middleware if the endpoint requires,
+// the endpoint is authorized as authorizedAs if it is not empty,e.g. batch create is authorized as create
+func (c *EntityController[T]) endpointHandlers(endpoint EntityEndpoint, handler context.Handler, authorizedAs ...EntityEndpoint) []context.Handler {
+	handlerList := make([]context.Handler, 0)
+	if len(authorizedAs) <= 0 {
+		authorizedAs = []EntityEndpoint{endpoint}
+	}
+	for _, eachEndpoint := range authorizedAs {
+		authorization, ok := c.Options.Authorizations[eachEndpoint]
+		if ok && !authorization.IsEmpty() {
+			handlerList = append(handlerList, GetMustAuthorizedMiddleware(authorization).Serve)
+		}
+	}
+	if c.Options.IdempotencyStore != nil && isIdempotencyEndpoint(endpoint) {
+		handlerList = append(handlerList, NewIdempotencyMiddleware(c.Options.IdempotencyStore, c.Options.IdempotencyTTL).Serve)
+	}
+	handlerList = append(handlerList, handler)
+	return handlerList
+}
+
 func (c *EntityController[T]) MergeAuthenticatedContextIfNeed(authenticatedDisabled bool, handlers ...context.Handler) []context.Handler {
 	return MergeAuthenticatedContextIfNeed(authenticatedDisabled, handlers...)
 }
@@ -98,18 +152,46 @@ func (c *EntityController[T]) All(ctx iris.Context) {
 	if c.Options.ListFilterFunc != nil {
 		c.Options.ListFilterFunc(new(T), filter, ctx)
 	}
+	if err := c.applyOwnerFilter(ctx, filter); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(filter, ctx.URLParamBoolDefault(IncludeDeletedParamName, false))
 	var list []*T
 	var err error
-	if len(filter) > 0 {
-		list, err = c.GetEntityService().FindList(filter)
+	if allMaxSize := c.Options.PaginationPolicy.allMaxSize(); allMaxSize > 0 {
+		// one more item to know whether the number of items exceeds the limit
+		list, err = c.findList(ctx, filter, nil, 1, int64(allMaxSize+1))
+	} else if len(filter) > 0 || len(c.Options.HiddenFields) > 0 {
+		list, err = c.findList(ctx, filter, nil, 0, 0)
 	} else {
 		list, err = c.GetEntityService().FindAll()
 	}
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	count, truncated, err := c.Options.PaginationPolicy.limitAll(len(list))
+	if err != nil {
+		HandleError(ctx, err)
 		return
 	}
-	controller.HandleSuccessWithListData(ctx, list, int64(len(list)))
+	list = list[:count]
+	total := int64(count)
+	if truncated {
+		total, err = c.GetEntityService().Count(filter)
+		if err != nil {
+			HandleErrorInternalServerError(ctx, err)
+			return
+		}
+		ctx.Header(AllTruncatedHeaderName, "true")
+	}
+	data, err := c.handleFindResult(ctx, list, getFieldsParam(ctx), getExpandParam(ctx))
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccessWithListData(ctx, data, total)
 }
 
 func (c *EntityController[T]) GetList(ctx iris.Context) {
@@ -121,173 +203,256 @@ func (c *EntityController[T]) GetList(ctx iris.Context) {
 
 	// params
 	pagination := MustGetPagination(ctx)
-	query := filter.MustGetFilterQuery(ctx.FormValue)
+	page, size, err := c.Options.PaginationPolicy.Normalize(pagination.Page, pagination.Size)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	pagination.Page, pagination.Size = page, size
 	sort := filter.MustGetSortOption(ctx.FormValue)
+	query, err := c.compileQueryFilter(ctx)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.applyOwnerFilter(ctx, query); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(query, ctx.URLParamBoolDefault(IncludeDeletedParamName, false))
+	if ctx.URLParamExists(CursorParamName) {
+		sorts, err := CursorSortsFromSortOption(sort)
+		if err != nil {
+			HandleError(ctx, err)
+			return
+		}
+		c.handleCursorList(ctx, query, sorts, ctx.URLParam(CursorParamName),
+			pagination.Size, ctx.URLParamBoolDefault(IncludeTotalParamName, false), getFieldsParam(ctx), getExpandParam(ctx))
+		return
+	}
 
+	sorts, err := sortsFromSortOption(sort)
+	if err == nil {
+		sorts, err = c.checkSorts(sorts, false)
+	}
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
 	service := c.GetEntityService()
-	list, err := service.FindList(query, mongodbr.MongodbrFindOptionWithSort(sort),
-		mongodbr.MongodbrFindOptionWithPage(int64(pagination.Page), int64(pagination.Size)))
+	list, err := c.findList(ctx, query, sorts, int64(pagination.Page), int64(pagination.Size))
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
 		return
 	}
 
 	count, err := service.Count(query)
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
 		return
 	}
-	controller.HandleSuccessWithListData(ctx, list, count)
+	data, err := c.handleFindResult(ctx, list, getFieldsParam(ctx), getExpandParam(ctx))
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccessWithListData(ctx, data, count)
 }
 
 func (c *EntityController[T]) Search(ctx iris.Context) {
 	input := &SearchInput{}
 	err := ctx.ReadJSON(input)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, err)
+		HandleErrorBadRequest(ctx, err)
 		return
 	}
 	err = mongodbr.Validate(input)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, err)
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	input.CurrentPage, input.PageSize, err = c.Options.PaginationPolicy.Normalize(input.CurrentPage, input.PageSize)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	query, err := c.compileSearchFilter(input)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.applyOwnerFilter(ctx, query); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(query, input.IncludeDeleted)
+	if input.Cursor != nil {
+		c.handleCursorList(ctx, query, CursorSortsFromSortInput(input.SortInput), *input.Cursor,
+			input.PageSize, input.IncludeTotal, input.Fields, input.Expand)
 		return
 	}
 
-	findOptions := make([]mongodbr.MongodbrFindOption, 0)
-	findOptions = append(findOptions, mongodbr.MongodbrFindOptionWithPage(int64(input.CurrentPage), int64(input.PageSize)))
-	findOptions = append(findOptions, SetupFindOptionsWithSort(input.SortInput)...)
+	sorts, err := c.checkSorts(sortsFromSortInput(input.SortInput), false)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
 	service := c.GetEntityService()
-	list, err := service.FindList(input.Filter, findOptions...)
+	list, err := c.findList(ctx, query, sorts, int64(input.CurrentPage), int64(input.PageSize))
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
 		return
 	}
 
-	count, err := service.Count(input.Filter)
+	count, err := service.Count(query)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	data, err := c.handleFindResult(ctx, list, input.Fields, input.Expand)
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleError(ctx, err)
 		return
 	}
-	controller.HandleSuccessWithTableData(ctx, list, count,
+	controller.HandleSuccessWithTableData(ctx, data, count,
 		controller.TableDataWithCurrentPage(input.CurrentPage),
 		controller.TableDataWithPageSize(input.PageSize))
 }
 
 // get by id
 func (c *EntityController[T]) GetById(ctx iris.Context) {
-	idValue := ctx.Params().Get("id")
-	if len(idValue) <= 0 {
-		controller.HandleErrorBadRequest(ctx, errors.New("id must not be empty"))
+	includeDeleted := ctx.URLParamBoolDefault(IncludeDeletedParamName, false)
+	id, item, err := c.findItemByIdParam(ctx, includeDeleted)
+	if err != nil {
+		HandleError(ctx, err)
 		return
 	}
-
-	id, err := primitive.ObjectIDFromHex(idValue)
-	if err != nil {
-		controller.HandleErrorBadRequest(ctx, fmt.Errorf("invalid id,id must be bson id format,id:%s", idValue))
+	if err = c.checkOwnerOfItem(ctx, id, item); err != nil {
+		HandleError(ctx, err)
 		return
 	}
-	item, err := c.GetEntityService().FindById(id)
-	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+	if err = c.setETag(ctx, item); err != nil {
+		HandleErrorInternalServerError(ctx, err)
 		return
 	}
-	if item == nil {
-		controller.HandleErrorInternalServerError(ctx, fmt.Errorf("invalid id,id:%s", idValue))
+	if c.Options.ConcurrencyControlEnabled {
+		ifNoneMatch := ctx.GetHeader("If-None-Match")
+		if len(ifNoneMatch) > 0 && matchETag(ifNoneMatch, ctx.ResponseWriter().Header().Get("ETag")) {
+			ctx.StatusCode(iris.StatusNotModified)
+			return
+		}
+	}
+	data, err := c.handleFindResult(ctx, item, getFieldsParam(ctx), getExpandParam(ctx))
+	if err != nil {
+		HandleError(ctx, err)
 		return
 	}
-	controller.HandleSuccessWithData(ctx, item)
+	controller.HandleSuccessWithData(ctx, data)
 }
 
 // create
 func (c *EntityController[T]) Create(ctx iris.Context) {
-	input := new(T)
-	err := ctx.ReadJSON(&input)
+	body, err := ctx.GetBody()
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, err)
+		HandleErrorBadRequest(ctx, err)
 		return
 	}
-	err = mongodbr.Validate(input)
+	input, err := c.prepareCreate(ctx, body)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, err)
+		HandleError(ctx, err)
 		return
 	}
 
-	// handler user info
-	c.SetUserInfo(ctx, input)
-
 	newItem, err := c.GetEntityService().Create(input)
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
 		return
 	}
-	controller.HandleSuccessWithData(ctx, newItem)
-}
-
-// update
-func (c *EntityController[T]) Update(ctx iris.Context) {
-	idValue := ctx.Params().Get("id")
-	if len(idValue) <= 0 {
-		controller.HandleErrorBadRequest(ctx, errors.New("id must not be empty"))
-		return
-	}
-	id, err := primitive.ObjectIDFromHex(idValue)
+	c.afterCreate(ctx, newItem)
+	data, err := c.hideFields(newItem)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, fmt.Errorf("invalid id,id must be bson id format,id:%s", idValue))
+		HandleErrorInternalServerError(ctx, err)
 		return
 	}
-	service := c.GetEntityService()
-	item, err := service.FindById(id)
+	controller.HandleSuccessWithData(ctx, data)
+}
+
+// audit the created items and run the after create hooks,
+// the items have been created,so the errors of hooks are only logged
+func (c *EntityController[T]) afterCreate(ctx iris.Context, items ...*T) {
+	c.auditCreate(ctx, items...)
+	for _, eachItem := range items {
+		if err := c.runAfterCreateHooks(ctx, eachItem); err != nil {
+			ctx.Application().Logger().Errorf("after create hook of item failed,%s", err.Error())
+		}
+	}
+}
+
+// decode and validate the create payload,set the user info and run the before create hooks
+func (c *EntityController[T]) prepareCreate(ctx iris.Context, body []byte) (*T, error) {
+	input, err := c.decodeCreate(body)
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
-		return
+		return nil, err
 	}
-	if item == nil {
-		controller.HandleErrorBadRequest(ctx, fmt.Errorf("not found item,id:%s", idValue))
-		return
+	if err = c.beforeCreate(ctx, input); err != nil {
+		return nil, err
 	}
+	return input, nil
+}
 
-	input := make(map[string]interface{})
-	err = ctx.ReadJSON(&input)
+// decode and validate the create payload
+func (c *EntityController[T]) decodeCreate(body []byte) (*T, error) {
+	input := new(T)
+	err := json.Unmarshal(body, input)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, err)
-		return
+		return nil, NewHttpError(iris.StatusBadRequest, err)
 	}
-
-	c.hookUpdate(ctx, input)
-	err = service.UpdateFields(id, input)
+	if err = c.checkCreateFields(input); err != nil {
+		return nil, err
+	}
+	err = mongodbr.Validate(input)
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
-		return
+		return nil, NewHttpError(iris.StatusBadRequest, err)
 	}
-	controller.HandleSuccess(ctx)
+	return input, nil
+}
+
+// set the user info and run the before create hooks
+func (c *EntityController[T]) beforeCreate(ctx iris.Context, input *T) error {
+	// handler user info
+	c.SetUserInfo(ctx, input)
+	return c.runBeforeCreateHooks(ctx, input)
 }
 
 // delete
 func (c *EntityController[T]) Delete(ctx iris.Context) {
-	idValue := ctx.Params().Get("id")
-	if len(idValue) <= 0 {
-		controller.HandleErrorBadRequest(ctx, errors.New("id must not be empty"))
-		return
-	}
-	oid, err := primitive.ObjectIDFromHex(idValue)
+	oid, item, err := c.findItemByIdParam(ctx, false)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, fmt.Errorf("invalid id format,err:%s", err.Error()))
+		HandleError(ctx, err)
 		return
 	}
-	service := c.GetEntityService()
-	item, err := service.FindById(oid)
-	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+	if err = c.checkOwnerOfItem(ctx, oid, item); err != nil {
+		HandleError(ctx, err)
 		return
 	}
-	if item == nil {
-		controller.HandleErrorBadRequest(ctx, fmt.Errorf("not found item,id:%s", idValue))
+
+	ids := []interface{}{oid}
+	if err = c.runBeforeDeleteHooks(ctx, ids); err != nil {
+		HandleError(ctx, err)
 		return
 	}
-
-	err = c.GetEntityService().Delete(oid)
+	if c.Options.SoftDeleteEnabled {
+		err = c.softDelete(ctx, []interface{}{oid})
+	} else {
+		err = c.deleteById(oid)
+	}
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	c.auditDelete(ctx, ids, item)
+	if err = c.runAfterDeleteHooks(ctx, ids); err != nil {
+		HandleError(ctx, err)
 		return
 	}
 	controller.HandleSuccess(ctx)
@@ -295,22 +460,43 @@ func (c *EntityController[T]) Delete(ctx iris.Context) {
 
 // delete
 func (c *EntityController[T]) DeleteList(ctx iris.Context) {
-	payload, err := GetBatchRequestPayload(ctx)
+	payload, err := GetIdsRequestPayload(ctx)
 	if err != nil {
-		controller.HandleErrorBadRequest(ctx, err)
+		HandleErrorBadRequest(ctx, err)
 		return
 	}
 	if len(payload.Ids) <= 0 {
 		controller.HandleSuccess(ctx)
 		return
 	}
-	filter := bson.M{
-		"_id": bson.M{"$in": payload.Ids},
+	ids, err := parseIds(c.idCodec(), payload.Ids)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	if err = c.checkOwnerOfIds(ctx, ids); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.runBeforeDeleteHooks(ctx, ids); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if c.Options.SoftDeleteEnabled {
+		err = c.softDelete(ctx, ids)
+	} else {
+		filter := bson.M{
+			"_id": bson.M{"$in": ids},
+		}
+		_, err = c.GetEntityService().DeleteMany(filter)
 	}
-
-	_, err = c.GetEntityService().DeleteMany(filter)
 	if err != nil {
-		controller.HandleErrorInternalServerError(ctx, err)
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	c.auditDelete(ctx, ids, nil)
+	if err = c.runAfterDeleteHooks(ctx, ids); err != nil {
+		HandleError(ctx, err)
 		return
 	}
 	controller.HandleSuccess(ctx)
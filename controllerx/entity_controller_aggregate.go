@@ -0,0 +1,208 @@
+package controllerx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/abmpio/mongodbr"
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// AggregateOperator is the operator of aggregate metric
+type AggregateOperator string
+
+const (
+	AggregateOperatorCount AggregateOperator = "count"
+	AggregateOperatorSum   AggregateOperator = "sum"
+	AggregateOperatorAvg   AggregateOperator = "avg"
+	AggregateOperatorMin   AggregateOperator = "min"
+	AggregateOperatorMax   AggregateOperator = "max"
+)
+
+// AggregateMetric is a metric computed for each group
+type AggregateMetric struct {
+	Op AggregateOperator `json:"op"`
+	// the field of metric,not required by count
+	Field string `json:"field"`
+	// the name of metric in result,count or op_field by default
+	As string `json:"as"`
+}
+
+func (m *AggregateMetric) name() string {
+	if len(m.As) > 0 {
+		return m.As
+	}
+	if m.Op == AggregateOperatorCount {
+		return string(AggregateOperatorCount)
+	}
+	return string(m.Op) + "_" + strings.ReplaceAll(m.Field, ".", "_")
+}
+
+type AggregateInput struct {
+	Filter map[string]interface{} `json:",inline"`
+	// conditions combined with Filter by and,the fields must be filterable by controller
+	Where *FilterExpression `json:"where"`
+	// include the soft deleted items
+	IncludeDeleted bool `json:"includeDeleted"`
+
+	// the fields to group by,all of the items are one group if it is empty
+	GroupBy []string `json:"groupBy"`
+	// the metrics of each group,count is used if it is empty
+	Metrics []AggregateMetric `json:"metrics"`
+}
+
+// aggregate,group the filtered items and compute the metrics of each group,
+// each result item contains the group by fields and the metrics,
+// the groups exceeding the max size of all are dropped and the truncated header is set
+func (c *EntityController[T]) Aggregate(ctx iris.Context) {
+	input := &AggregateInput{}
+	err := ctx.ReadJSON(input)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	err = mongodbr.Validate(input)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	query, err := c.compileFilter(input.Filter, input.Where)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.applyOwnerFilter(ctx, query); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(query, input.IncludeDeleted)
+	pipeline, err := c.aggregatePipeline(query, input)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+
+	collection, err := c.GetCollection()
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	cursor, err := collection.Aggregate(ctx.Request().Context(), pipeline)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	rows := make([]bson.M, 0)
+	if err = cursor.All(ctx.Request().Context(), &rows); err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	// one more group is found to know whether the groups are truncated
+	if maxSize := c.Options.PaginationPolicy.allMaxSize(); maxSize > 0 && len(rows) > maxSize {
+		rows = rows[:maxSize]
+		ctx.Header(AllTruncatedHeaderName, "true")
+	}
+	list := make([]map[string]interface{}, 0, len(rows))
+	for _, eachRow := range rows {
+		list = append(list, aggregateResultItem(eachRow, input))
+	}
+	controller.HandleSuccessWithListData(ctx, list, int64(len(list)))
+}
+
+// build $match,$group,$sort and $limit stages,
+// the group keys are g0,g1... to avoid the invalid key names
+func (c *EntityController[T]) aggregatePipeline(query bson.M, input *AggregateInput) (bson.A, error) {
+	groupId := bson.M{}
+	for i, eachField := range input.GroupBy {
+		if !c.isAggregatable(eachField) {
+			return nil, NewValidationError(fmt.Errorf("field %s can not be grouped by", eachField), FieldError{
+				Field:   eachField,
+				Code:    FieldErrorCodeNotAllowed,
+				Message: "field can not be grouped by",
+			})
+		}
+		groupId[fmt.Sprintf("g%d", i)] = "$" + c.bsonFieldName(eachField)
+	}
+	metrics := input.Metrics
+	if len(metrics) <= 0 {
+		metrics = []AggregateMetric{{Op: AggregateOperatorCount}}
+	}
+
+	group := bson.M{"_id": groupId}
+	if len(groupId) <= 0 {
+		group["_id"] = nil
+	}
+	names := make(map[string]struct{})
+	for _, eachField := range input.GroupBy {
+		names[eachField] = struct{}{}
+	}
+	for _, eachMetric := range metrics {
+		name := eachMetric.name()
+		if !isSafeFilterField(name) || strings.Contains(name, ".") {
+			return nil, NewHttpErrorf(iris.StatusBadRequest, "invalid metric name:%s", name)
+		}
+		// _id is the group key of $group
+		if name == "_id" {
+			return nil, NewHttpErrorf(iris.StatusBadRequest, "reserved metric name:%s", name)
+		}
+		if _, ok := names[name]; ok {
+			return nil, NewHttpErrorf(iris.StatusBadRequest, "duplicated metric name:%s", name)
+		}
+		names[name] = struct{}{}
+		switch eachMetric.Op {
+		case AggregateOperatorCount:
+			group[name] = bson.M{"$sum": 1}
+		case AggregateOperatorSum, AggregateOperatorAvg, AggregateOperatorMin, AggregateOperatorMax:
+			if !c.isAggregatable(eachMetric.Field) {
+				return nil, NewValidationError(fmt.Errorf("field %s can not be aggregated", eachMetric.Field), FieldError{
+					Field:   eachMetric.Field,
+					Code:    FieldErrorCodeNotAllowed,
+					Message: "field can not be aggregated",
+				})
+			}
+			group[name] = bson.M{"$" + string(eachMetric.Op): "$" + c.bsonFieldName(eachMetric.Field)}
+		default:
+			return nil, NewHttpErrorf(iris.StatusBadRequest, "unsupported metric op:%s", eachMetric.Op)
+		}
+	}
+
+	pipeline := bson.A{
+		bson.M{"$match": query},
+		bson.M{"$group": group},
+		bson.M{"$sort": bson.M{"_id": 1}},
+	}
+	if maxSize := c.Options.PaginationPolicy.allMaxSize(); maxSize > 0 {
+		pipeline = append(pipeline, bson.M{"$limit": maxSize + 1})
+	}
+	return pipeline, nil
+}
+
+func (c *EntityController[T]) isAggregatable(field string) bool {
+	if len(field) <= 0 || !isSafeFilterField(field) || c.isHiddenField(field) {
+		return false
+	}
+	for _, eachField := range c.Options.AggregatableFields {
+		if eachField == field {
+			return true
+		}
+	}
+	return false
+}
+
+// convert the $group result to the item with group by fields and metrics
+func aggregateResultItem(row bson.M, input *AggregateInput) map[string]interface{} {
+	item := make(map[string]interface{}, len(row)+len(input.GroupBy))
+	groupId, _ := row["_id"].(bson.M)
+	for i, eachField := range input.GroupBy {
+		item[eachField] = groupId[fmt.Sprintf("g%d", i)]
+	}
+	for key, value := range row {
+		if key == "_id" {
+			continue
+		}
+		item[key] = value
+	}
+	return item
+}
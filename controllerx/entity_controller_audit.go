@@ -0,0 +1,245 @@
+package controllerx
+
+import (
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// the name of entity in audit records,the type name of T by default
+func (c *EntityController[T]) auditEntityName() string {
+	if len(c.Options.AuditEntityName) > 0 {
+		return c.Options.AuditEntityName
+	}
+	return reflect.TypeOf(new(T)).Elem().Name()
+}
+
+// the audit sink,the audit collection in the database of entity collection by default
+func (c *EntityController[T]) auditSink() (IAuditSink, error) {
+	if c.Options.AuditSink != nil {
+		return c.Options.AuditSink, nil
+	}
+	collection, err := c.GetCollection()
+	if err != nil {
+		return nil, err
+	}
+	return NewMongoAuditSink(collection.Database().Collection(DefaultAuditCollectionName)), nil
+}
+
+// new audit record,the hidden fields are removed from changes
+func (c *EntityController[T]) newAuditRecord(ctx iris.Context, action AuditAction, id interface{}, changes []AuditFieldChange) *AuditRecord {
+	if len(c.Options.HiddenFields) > 0 {
+		hiddenPaths := make([]string, 0, len(c.Options.HiddenFields))
+		for _, eachField := range c.Options.HiddenFields {
+			hiddenPaths = append(hiddenPaths, c.bsonFieldName(eachField))
+		}
+		changes = hideAuditChanges(changes, hiddenPaths)
+	}
+	return &AuditRecord{
+		Entity:   c.auditEntityName(),
+		EntityId: c.idCodec().Format(id),
+		Action:   action,
+		UserId:   GetUserId(ctx),
+		ClientIp: ctx.RemoteAddr(),
+		Time:     time.Now(),
+		Changes:  changes,
+	}
+}
+
+// write the audit records,the change has been saved so the error is only logged
+func (c *EntityController[T]) writeAudit(ctx iris.Context, records ...*AuditRecord) {
+	if !c.Options.AuditEnabled || len(records) <= 0 {
+		return
+	}
+	sink, err := c.auditSink()
+	if err == nil {
+		err = sink.Write(ctx.Request().Context(), records)
+	}
+	if err != nil {
+		ctx.Application().Logger().Errorf("write audit records failed,%s", err.Error())
+	}
+}
+
+// record the creation of items with all of their fields
+func (c *EntityController[T]) auditCreate(ctx iris.Context, items ...*T) {
+	if !c.Options.AuditEnabled {
+		return
+	}
+	records := make([]*AuditRecord, 0, len(items))
+	for _, eachItem := range items {
+		m, err := entityToBsonM(eachItem)
+		if err != nil {
+			ctx.Application().Logger().Errorf("write audit records failed,%s", err.Error())
+			continue
+		}
+		records = append(records, c.newAuditRecord(ctx, AuditActionCreate, m["_id"], auditChanges(nil, m, nil)))
+	}
+	c.writeAudit(ctx, records...)
+}
+
+// record the deletion of items,the fields of item are recorded if it is not nil
+func (c *EntityController[T]) auditDelete(ctx iris.Context, ids []interface{}, item *T) {
+	if !c.Options.AuditEnabled {
+		return
+	}
+	var changes []AuditFieldChange
+	if item != nil {
+		m, err := entityToBsonM(item)
+		if err == nil {
+			changes = auditChanges(m, nil, mapKeysOfBsonM(m))
+		}
+	}
+	records := make([]*AuditRecord, 0, len(ids))
+	for _, eachId := range ids {
+		records = append(records, c.newAuditRecord(ctx, AuditActionDelete, eachId, changes))
+	}
+	c.writeAudit(ctx, records...)
+}
+
+// the changes of set and unset,the old values are taken from item if it is not nil
+func (c *EntityController[T]) auditUpdateChanges(item *T, set bson.M, unset bson.M) []AuditFieldChange {
+	if !c.Options.AuditEnabled {
+		return nil
+	}
+	var original bson.M
+	if item != nil {
+		original, _ = entityToBsonM(item)
+	}
+	return auditChanges(original, set, mapKeysOfBsonM(unset))
+}
+
+// the changes from original to the values of set and the removed fields,sorted by field
+func auditChanges(original bson.M, set bson.M, removed []string) []AuditFieldChange {
+	changes := make([]AuditFieldChange, 0, len(set)+len(removed))
+	for key, value := range set {
+		change := AuditFieldChange{Field: key, New: value}
+		if original != nil {
+			change.Old = original[key]
+		}
+		changes = append(changes, change)
+	}
+	for _, eachKey := range removed {
+		if _, ok := set[eachKey]; ok {
+			continue
+		}
+		change := AuditFieldChange{Field: eachKey}
+		if original != nil {
+			change.Old = original[eachKey]
+		}
+		changes = append(changes, change)
+	}
+	sort.Slice(changes, func(i, j int) bool {
+		return changes[i].Field < changes[j].Field
+	})
+	return changes
+}
+
+// remove the changes of the hidden bson paths,
+// the hidden fields nested in the changed values are removed from the copies of the values
+func hideAuditChanges(changes []AuditFieldChange, hiddenPaths []string) []AuditFieldChange {
+	visible := make([]AuditFieldChange, 0, len(changes))
+	for _, eachChange := range changes {
+		hidden := false
+		for _, eachPath := range hiddenPaths {
+			if eachChange.Field == eachPath || strings.HasPrefix(eachChange.Field, eachPath+".") {
+				hidden = true
+				break
+			}
+			if nestedPath, ok := strings.CutPrefix(eachPath, eachChange.Field+"."); ok {
+				path := strings.Split(nestedPath, ".")
+				eachChange.Old = removeBsonPath(eachChange.Old, path)
+				eachChange.New = removeBsonPath(eachChange.New, path)
+			}
+		}
+		if !hidden {
+			visible = append(visible, eachChange)
+		}
+	}
+	return visible
+}
+
+// the copy of value without the field at path,value is returned as is if it is not a document
+func removeBsonPath(value interface{}, path []string) interface{} {
+	switch v := value.(type) {
+	case bson.M:
+		copied := make(bson.M, len(v))
+		for key, eachValue := range v {
+			copied[key] = eachValue
+		}
+		if len(path) == 1 {
+			delete(copied, path[0])
+		} else if child, ok := copied[path[0]]; ok {
+			copied[path[0]] = removeBsonPath(child, path[1:])
+		}
+		return copied
+	case bson.D:
+		copied := make(bson.D, 0, len(v))
+		for _, eachElement := range v {
+			if eachElement.Key != path[0] {
+				copied = append(copied, eachElement)
+				continue
+			}
+			if len(path) > 1 {
+				copied = append(copied, bson.E{Key: eachElement.Key, Value: removeBsonPath(eachElement.Value, path[1:])})
+			}
+		}
+		return copied
+	}
+	return value
+}
+
+func mapKeysOfBsonM(m bson.M) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// history,the audit records of the item,the newest first.
+// the history of a hard deleted item is available unless the controller is owner scoped
+func (c *EntityController[T]) History(ctx iris.Context) {
+	idValue := ctx.Params().Get("id")
+	id, err := c.idCodec().Parse(idValue)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	item, err := c.findById(id)
+	if err != nil && !IsNotFoundError(err) {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	if item != nil {
+		if err = c.checkOwnerOfItem(ctx, id, item); err != nil {
+			HandleError(ctx, err)
+			return
+		}
+	} else if c.isOwnerScoped() {
+		HandleError(ctx, NewNotFoundError(idValue))
+		return
+	}
+
+	pagination := MustGetPagination(ctx)
+	page, size, err := c.Options.PaginationPolicy.Normalize(pagination.Page, pagination.Size)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	sink, err := c.auditSink()
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	records, total, err := sink.FindHistory(ctx.Request().Context(), c.auditEntityName(), c.idCodec().Format(id), int64(page), int64(size))
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	controller.HandleSuccessWithListData(ctx, records, total)
+}
@@ -0,0 +1,101 @@
+package controllerx
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestAuditChanges(t *testing.T) {
+	tests := []struct {
+		name     string
+		original bson.M
+		set      bson.M
+		removed  []string
+		want     []AuditFieldChange
+	}{
+		{
+			"create",
+			nil,
+			bson.M{"name": "a", "age": 1},
+			nil,
+			[]AuditFieldChange{{Field: "age", New: 1}, {Field: "name", New: "a"}},
+		},
+		{
+			"update",
+			bson.M{"name": "a", "note": "n"},
+			bson.M{"name": "b"},
+			[]string{"note"},
+			[]AuditFieldChange{{Field: "name", Old: "a", New: "b"}, {Field: "note", Old: "n"}},
+		},
+		{
+			"removed and set",
+			bson.M{"name": "a"},
+			bson.M{"name": "b"},
+			[]string{"name"},
+			[]AuditFieldChange{{Field: "name", Old: "a", New: "b"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := auditChanges(tt.original, tt.set, tt.removed)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHideAuditChanges(t *testing.T) {
+	profile := bson.M{"city": "x", "phone": "p"}
+	tests := []struct {
+		name    string
+		changes []AuditFieldChange
+		hidden  []string
+		want    []AuditFieldChange
+	}{
+		{
+			"no hidden fields",
+			[]AuditFieldChange{{Field: "name", New: "a"}},
+			nil,
+			[]AuditFieldChange{{Field: "name", New: "a"}},
+		},
+		{
+			"hidden field",
+			[]AuditFieldChange{{Field: "name", New: "a"}, {Field: "password", Old: "x", New: "y"}},
+			[]string{"password"},
+			[]AuditFieldChange{{Field: "name", New: "a"}},
+		},
+		{
+			"under hidden field",
+			[]AuditFieldChange{{Field: "secret.key", New: "a"}},
+			[]string{"secret"},
+			[]AuditFieldChange{},
+		},
+		{
+			"hidden field nested in value",
+			[]AuditFieldChange{{Field: "profile", Old: profile, New: bson.D{{Key: "city", Value: "y"}, {Key: "phone", Value: "q"}}}},
+			[]string{"profile.phone"},
+			[]AuditFieldChange{{Field: "profile", Old: bson.M{"city": "x"}, New: bson.D{{Key: "city", Value: "y"}}}},
+		},
+		{
+			"prefix of other field",
+			[]AuditFieldChange{{Field: "passwordHint", New: "a"}},
+			[]string{"password"},
+			[]AuditFieldChange{{Field: "passwordHint", New: "a"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := hideAuditChanges(tt.changes, tt.hidden)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+		})
+	}
+	// the changed values are shared with the update,so they must not be changed
+	if _, ok := profile["phone"]; !ok {
+		t.Fatal("the original value is changed")
+	}
+}
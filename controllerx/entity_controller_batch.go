@@ -0,0 +1,247 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// BatchItemResult is the result of an item of batch payload
+type BatchItemResult struct {
+	// index of the item in payload,from 0
+	Index int `json:"index"`
+	// id of the created or updated item
+	Id string `json:"id,omitempty"`
+	// 201 for created,200 for updated,the error status otherwise
+	Status  int          `json:"status"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// BatchResult is the report of batch create,update and upsert,
+// the items are processed one by one,so the failed items do not stop the others
+type BatchResult struct {
+	Total     int               `json:"total"`
+	Succeeded int               `json:"succeeded"`
+	Failed    int               `json:"failed"`
+	Items     []BatchItemResult `json:"items"`
+}
+
+func (r *BatchResult) add(item BatchItemResult) {
+	if item.Status >= iris.StatusBadRequest {
+		r.Failed++
+	} else {
+		r.Succeeded++
+	}
+	r.Items = append(r.Items, item)
+}
+
+// create the items of json array in body
+func (c *EntityController[T]) CreateBatch(ctx iris.Context) {
+	c.handleBatch(ctx, func(data json.RawMessage) (interface{}, bool, error) {
+		id, err := c.createItem(ctx, data)
+		return id, true, err
+	})
+}
+
+// update the items of json array in body by their ids,
+// each item replaces the whole document like PUT /{id}
+func (c *EntityController[T]) UpdateBatch(ctx iris.Context) {
+	c.handleBatch(ctx, func(data json.RawMessage) (interface{}, bool, error) {
+		fields := make(map[string]interface{})
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, false, NewHttpError(iris.StatusBadRequest, err)
+		}
+		idValue, err := c.idOfPayload(fields)
+		if err != nil {
+			return nil, false, err
+		}
+		id, err := c.idCodec().Parse(idValue)
+		if err != nil {
+			return nil, false, NewHttpError(iris.StatusBadRequest, err)
+		}
+		item, err := c.findById(id)
+		if err != nil && !IsNotFoundError(err) {
+			return id, false, err
+		}
+		if item == nil || c.isSoftDeleted(item) {
+			return id, false, NewNotFoundError(idValue)
+		}
+		return id, false, c.replaceBatchItem(ctx, id, item, data)
+	})
+}
+
+// create or update the items of json array in body by the upsert keys,
+// an unique index of the keys is required to avoid the duplicated items created by concurrent requests
+func (c *EntityController[T]) Upsert(ctx iris.Context) {
+	if len(c.Options.UpsertKeys) <= 0 {
+		HandleError(ctx, NewHttpErrorf(iris.StatusNotImplemented, "upsert keys are not configured"))
+		return
+	}
+	c.handleBatch(ctx, func(data json.RawMessage) (interface{}, bool, error) {
+		query, err := c.upsertFilter(data)
+		if err != nil {
+			return nil, false, err
+		}
+		if err = c.applyOwnerFilter(ctx, query); err != nil {
+			return nil, false, err
+		}
+		c.applySoftDeleteFilter(query, false)
+		list, err := c.GetEntityService().FindList(query)
+		if err != nil {
+			return nil, false, err
+		}
+		if len(list) > 1 {
+			return nil, false, NewHttpErrorf(iris.StatusConflict, "%d items match the upsert keys %s", len(list), strings.Join(c.Options.UpsertKeys, ","))
+		}
+		if len(list) <= 0 {
+			id, err := c.createItem(ctx, data)
+			return id, true, err
+		}
+		m, err := entityToBsonM(list[0])
+		if err != nil {
+			return nil, false, err
+		}
+		id := m["_id"]
+		return id, false, c.replaceBatchItem(ctx, id, list[0], data)
+	})
+}
+
+// read the json array in body and process each item by handle,
+// handle returns the id of item,whether it is created and the error
+func (c *EntityController[T]) handleBatch(ctx iris.Context, handle func(data json.RawMessage) (interface{}, bool, error)) {
+	rows, err := ReadImportRows(ImportFormatJson, ctx.Request().Body, c.Options.PaginationPolicy.allMaxSize(), nil)
+	if err != nil {
+		var httpErr *HttpError
+		if errors.As(err, &httpErr) {
+			HandleError(ctx, err)
+			return
+		}
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	result := &BatchResult{
+		Total: len(rows),
+		Items: make([]BatchItemResult, 0, len(rows)),
+	}
+	for i, eachRow := range rows {
+		id, created, err := handle(eachRow.Data)
+		item := BatchItemResult{
+			Index:  i,
+			Status: iris.StatusOK,
+		}
+		if created {
+			item.Status = iris.StatusCreated
+		}
+		if id != nil {
+			item.Id = c.idCodec().Format(id)
+		}
+		if err != nil {
+			item.Status, item.Code, item.Details = GetErrorDetails(err)
+			item.Error = err.Error()
+		}
+		result.add(item)
+	}
+	controller.HandleSuccessWithData(ctx, result)
+}
+
+// create the item of payload and return its id
+func (c *EntityController[T]) createItem(ctx iris.Context, data json.RawMessage) (interface{}, error) {
+	input, err := c.prepareCreate(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	newItem, err := c.GetEntityService().Create(input)
+	if err != nil {
+		return nil, err
+	}
+	c.afterCreate(ctx, newItem)
+	m, err := entityToBsonM(newItem)
+	if err != nil {
+		return nil, err
+	}
+	return m["_id"], nil
+}
+
+// check the ownership and replace the item,the version loaded is checked when concurrency control is enabled
+func (c *EntityController[T]) replaceBatchItem(ctx iris.Context, id interface{}, item *T, data json.RawMessage) error {
+	if err := c.checkOwnerOfItem(ctx, id, item); err != nil {
+		return err
+	}
+	var versionFilter bson.M
+	if c.Options.ConcurrencyControlEnabled {
+		token, err := c.concurrencyToken(item)
+		if err != nil {
+			return err
+		}
+		versionFilter = bson.M{c.concurrencyField(): token}
+	}
+	return c.replaceItem(ctx, id, item, versionFilter, data)
+}
+
+// get the id of item payload,it is the json or bson name of _id field
+func (c *EntityController[T]) idOfPayload(fields map[string]interface{}) (string, error) {
+	names := []string{"_id", "id"}
+	if idField := GetEntityFields(new(T)).Get("_id"); idField != nil {
+		names = idField.Names()
+	}
+	for _, eachName := range names {
+		value, ok := fields[eachName]
+		if !ok {
+			continue
+		}
+		if idValue, ok := value.(string); ok && len(idValue) > 0 {
+			return idValue, nil
+		}
+		return "", NewHttpErrorf(iris.StatusBadRequest, "invalid id:%v", value)
+	}
+	return "", NewValidationError(fmt.Errorf("id must not be empty"), FieldError{
+		Field:   names[0],
+		Code:    "required",
+		Message: "id must not be empty",
+	})
+}
+
+// the filter matching the upsert keys of payload,the values are converted to the field types of T
+func (c *EntityController[T]) upsertFilter(data json.RawMessage) (bson.M, error) {
+	input := new(T)
+	if err := json.Unmarshal(data, input); err != nil {
+		return nil, NewHttpError(iris.StatusBadRequest, err)
+	}
+	m, err := entityToBsonM(input)
+	if err != nil {
+		return nil, err
+	}
+	query := bson.M{}
+	for _, eachKey := range c.Options.UpsertKeys {
+		key := c.bsonFieldName(eachKey)
+		value, ok := bsonPathGet(m, strings.Split(key, "."))
+		if !ok || value == nil || value == "" {
+			return nil, NewValidationError(fmt.Errorf("upsert key %s must not be empty", eachKey), FieldError{
+				Field:   eachKey,
+				Code:    "required",
+				Message: "upsert key must not be empty",
+			})
+		}
+		query[key] = value
+	}
+	return query, nil
+}
+
+func bsonPathGet(m bson.M, path []string) (interface{}, bool) {
+	value, ok := m[path[0]]
+	if !ok || len(path) == 1 {
+		return value, ok
+	}
+	child, ok := value.(bson.M)
+	if !ok {
+		return nil, false
+	}
+	return bsonPathGet(child, path[1:])
+}
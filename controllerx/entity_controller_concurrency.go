@@ -0,0 +1,115 @@
+package controllerx
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/abmpio/mongodbr"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// default field used as concurrency token when VersionField is not set
+	LastModificationTimeField = "lastModificationTime"
+)
+
+var (
+	ErrPreconditionFailed   = NewHttpErrorf(iris.StatusPreconditionFailed, "item has been modified by others,reload it and try again")
+	ErrPreconditionRequired = NewHttpErrorf(iris.StatusPreconditionRequired, "If-Match header is required,get the item and send its ETag")
+)
+
+// the concurrency token must change on each update,otherwise the ETag is constant and the check never fails,
+// so either VersionField is set or T stamps lastModificationTime
+func (c *EntityController[T]) checkConcurrencyOptions() error {
+	if !c.Options.ConcurrencyControlEnabled || len(c.Options.VersionField) > 0 {
+		return nil
+	}
+	if _, ok := interface{}(new(T)).(mongodbr.IModificationEntity); ok {
+		return nil
+	}
+	return fmt.Errorf("concurrency control of %T requires VersionField or the entity implementing mongodbr.IModificationEntity", new(T))
+}
+
+// the field used as concurrency token
+func (c *EntityController[T]) concurrencyField() string {
+	if len(c.Options.VersionField) > 0 {
+		return c.Options.VersionField
+	}
+	return LastModificationTimeField
+}
+
+// current value of concurrency token
+func (c *EntityController[T]) concurrencyToken(item *T) (interface{}, error) {
+	m, err := entityToBsonM(item)
+	if err != nil {
+		return nil, err
+	}
+	return m[c.concurrencyField()], nil
+}
+
+// set ETag header of item
+func (c *EntityController[T]) setETag(ctx iris.Context, item *T) error {
+	if !c.Options.ConcurrencyControlEnabled {
+		return nil
+	}
+	token, err := c.concurrencyToken(item)
+	if err != nil {
+		return err
+	}
+	ctx.Header("ETag", formatETag(token))
+	return nil
+}
+
+// check If-Match header and return the filter which matches the current version
+func (c *EntityController[T]) checkIfMatch(ctx iris.Context, item *T) (bson.M, error) {
+	token, err := c.concurrencyToken(item)
+	if err != nil {
+		return nil, err
+	}
+	ifMatch := ctx.GetHeader("If-Match")
+	if len(ifMatch) <= 0 && c.Options.IfMatchRequired {
+		return nil, ErrPreconditionRequired
+	}
+	if len(ifMatch) > 0 && !matchETag(ifMatch, formatETag(token)) {
+		return nil, ErrPreconditionFailed
+	}
+	// nil matches the document without the field
+	return bson.M{c.concurrencyField(): token}, nil
+}
+
+// format the concurrency token as ETag
+func formatETag(token interface{}) string {
+	var v string
+	switch t := token.(type) {
+	case nil:
+		v = "0"
+	case primitive.DateTime:
+		v = fmt.Sprintf("%d", int64(t))
+	case time.Time:
+		v = fmt.Sprintf("%d", t.UnixMilli())
+	case *time.Time:
+		v = fmt.Sprintf("%d", t.UnixMilli())
+	default:
+		v = fmt.Sprint(t)
+	}
+	return fmt.Sprintf("\"%s\"", v)
+}
+
+// header is the value of If-Match or If-None-Match header,
+// which can be "*" or a list of ETags
+func matchETag(header string, etag string) bool {
+	for _, eachValue := range strings.Split(header, ",") {
+		eachValue = strings.TrimSpace(eachValue)
+		if eachValue == "*" {
+			return true
+		}
+		eachValue = strings.TrimPrefix(eachValue, "W/")
+		if eachValue == etag {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,245 @@
+package controllerx
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/abmpio/entity/filter"
+	"github.com/abmpio/mongodbr"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// struct tag of the export header,e.g. `export:"User Name"`,the field is not exported if it is "-"
+	ExportFieldTagName = "export"
+	// query parameter or export input field of the file format
+	ExportFormatParamName = "format"
+)
+
+// ExportColumn is a column of the exported file
+type ExportColumn struct {
+	// json field of entity,nested field is separated by "."
+	Field  string
+	Header string
+}
+
+type ExportInput struct {
+	Filter map[string]interface{} `json:",inline"`
+	// conditions combined with Filter by and,the fields must be filterable by controller
+	Where *FilterExpression `json:"where"`
+	// include the soft deleted items
+	IncludeDeleted bool `json:"includeDeleted"`
+	// csv,ndjson or xlsx,csv by default
+	Format ExportFormat `json:"format"`
+	// the exported fields in order,all of the export columns if it is empty
+	Fields []string `json:"fields"`
+
+	SortInput
+}
+
+// export columns of T,taken from ExportColumns option or the json fields of T and their export tags
+func (c *EntityController[T]) exportColumns() []ExportColumn {
+	if len(c.Options.ExportColumns) > 0 {
+		return c.Options.ExportColumns
+	}
+	hiddenFields := make(map[string]struct{})
+	for _, eachField := range c.fieldProjection(nil).Exclude {
+		hiddenFields[eachField] = struct{}{}
+	}
+	columns := make([]ExportColumn, 0)
+	for _, eachField := range GetEntityFields(new(T)).List {
+		if len(eachField.JsonName) <= 0 {
+			continue
+		}
+		if _, ok := hiddenFields[eachField.JsonName]; ok {
+			continue
+		}
+		header, _, _ := strings.Cut(eachField.Tag.Get(ExportFieldTagName), ",")
+		if header == "-" {
+			continue
+		}
+		if len(header) <= 0 {
+			header = eachField.JsonName
+		}
+		columns = append(columns, ExportColumn{Field: eachField.JsonName, Header: header})
+	}
+	return columns
+}
+
+// select the columns of fields in order,unknown field is rejected
+func (c *EntityController[T]) selectExportColumns(fields []string) ([]ExportColumn, error) {
+	columns := c.exportColumns()
+	names := make([]string, 0)
+	for _, eachField := range fields {
+		for _, eachName := range strings.Split(eachField, ",") {
+			if eachName = strings.TrimSpace(eachName); len(eachName) > 0 {
+				names = append(names, eachName)
+			}
+		}
+	}
+	if len(names) <= 0 {
+		return columns, nil
+	}
+	selected := make([]ExportColumn, 0, len(names))
+	for _, eachName := range names {
+		found := false
+		for _, eachColumn := range columns {
+			if eachColumn.Field == eachName {
+				selected = append(selected, eachColumn)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, NewHttpErrorf(iris.StatusBadRequest, "field %s can not be exported", eachName)
+		}
+	}
+	return selected, nil
+}
+
+// export by query string,the filter and sort are the same as GetList
+func (c *EntityController[T]) ExportByQuery(ctx iris.Context) {
+	query, err := c.compileQueryFilter(ctx)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.applyOwnerFilter(ctx, query); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(query, ctx.URLParamBoolDefault(IncludeDeletedParamName, false))
+	format := ExportFormat(ctx.URLParamDefault(ExportFormatParamName, string(ExportFormatCsv)))
+	sorts, err := CursorSortsFromSortOption(filter.MustGetSortOption(ctx.FormValue))
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.export(ctx, query, sorts, format, getFieldsParam(ctx))
+}
+
+// export by the search input in body
+func (c *EntityController[T]) Export(ctx iris.Context) {
+	input := &ExportInput{}
+	err := ctx.ReadJSON(input)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	err = mongodbr.Validate(input)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	query, err := c.compileFilter(input.Filter, input.Where)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.applyOwnerFilter(ctx, query); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(query, input.IncludeDeleted)
+	format := input.Format
+	if len(format) <= 0 {
+		format = ExportFormatCsv
+	}
+	c.export(ctx, query, CursorSortsFromSortInput(input.SortInput), format, input.Fields)
+}
+
+// stream the items matching query to the response with a mongo cursor
+func (c *EntityController[T]) export(ctx iris.Context, query bson.M, sorts []CursorSort, format ExportFormat, fields []string) {
+	if !format.IsValid() {
+		HandleErrorBadRequest(ctx, fmt.Errorf("unsupported export format:%s", format))
+		return
+	}
+	columns, err := c.selectExportColumns(fields)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	collection, err := c.GetCollection()
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	sorts, err = c.checkSorts(sorts, false)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	findOptions := options.Find().SetSort(mongoSort(sorts))
+	if projection := c.hiddenProjection(); projection != nil {
+		findOptions.SetProjection(projection)
+	}
+	cursor, err := collection.Find(ctx.Request().Context(), query, findOptions)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	defer cursor.Close(ctx.Request().Context())
+
+	fileName := c.Options.ExportFileName
+	if len(fileName) <= 0 {
+		fileName = "export"
+	}
+	ctx.ContentType(format.ContentType())
+	ctx.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", fileName+"."+string(format)))
+	w := ctx.ResponseWriter()
+	writer, err := NewExportWriter(format, w, columns)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+
+	projection := c.fieldProjection(nil)
+	paths := make([][]string, 0, len(columns))
+	for _, eachColumn := range columns {
+		paths = append(paths, strings.Split(eachColumn.Field, "."))
+	}
+	count := 0
+	for cursor.Next(ctx.Request().Context()) {
+		item := new(T)
+		if err = cursor.Decode(item); err != nil {
+			break
+		}
+		var data interface{}
+		data, err = c.runAfterFindHooks(ctx, item)
+		if err != nil {
+			break
+		}
+		data, err = toJsonValue(data)
+		if err != nil {
+			break
+		}
+		data = projection.applyJsonValue(data)
+		values := make([]interface{}, 0, len(paths))
+		for _, eachPath := range paths {
+			value, _ := jsonPointerGet(data, eachPath)
+			values = append(values, value)
+		}
+		if err = writer.WriteRow(values); err != nil {
+			break
+		}
+		count++
+		if flusher, ok := w.(http.Flusher); ok && count%1000 == 0 {
+			flusher.Flush()
+		}
+	}
+	if err == nil {
+		err = cursor.Err()
+	}
+	if err != nil {
+		// the response has been partially written,the error can not be returned as problem
+		ctx.Application().Logger().Errorf("export failed,%s", err.Error())
+		ctx.StopExecution()
+		return
+	}
+	if err = writer.Close(); err != nil {
+		ctx.Application().Logger().Errorf("export failed,%s", err.Error())
+	}
+}
@@ -0,0 +1,164 @@
+package controllerx
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// code of FieldError when the field can not be set by client
+const FieldErrorCodeNotAllowed = "not_allowed"
+
+// fields managed by server,they can not be set by client
+var ServerManagedFields = []string{
+	"creatorId",
+	"creationTime",
+	"lastModificationTime",
+	"lastModifierId",
+}
+
+// the fields can not be set by create
+func (c *EntityController[T]) createProtectedFields() map[string]struct{} {
+	fields := map[string]struct{}{
+		"_id": {},
+		"id":  {},
+	}
+	for _, eachField := range ServerManagedFields {
+		fields[eachField] = struct{}{}
+	}
+	if c.Options.SoftDeleteEnabled {
+		fields[SoftDeleteFieldIsDeleted] = struct{}{}
+		fields[SoftDeleteFieldDeletionTime] = struct{}{}
+		fields[SoftDeleteFieldDeleterId] = struct{}{}
+	}
+	if len(c.Options.VersionField) > 0 {
+		fields[c.Options.VersionField] = struct{}{}
+	}
+	for _, eachField := range GetEntityFields(new(T)).List {
+		if eachField.HasTagOption(EntityFieldTagReadonly) {
+			for _, eachName := range eachField.Names() {
+				fields[eachName] = struct{}{}
+			}
+		}
+	}
+	return fields
+}
+
+// the fields can not be set by update
+func (c *EntityController[T]) updateProtectedFields() map[string]struct{} {
+	fields := c.createProtectedFields()
+	for _, eachField := range GetEntityFields(new(T)).List {
+		if eachField.HasTagOption(EntityFieldTagCreateonly) {
+			for _, eachName := range eachField.Names() {
+				fields[eachName] = struct{}{}
+			}
+		}
+	}
+	return fields
+}
+
+// check the fields set by the decoded create payload,
+// the fields are taken from the decoded item because json matches the keys case insensitively
+func (c *EntityController[T]) checkCreateFields(input *T) error {
+	fields, err := changedFields(new(T), input)
+	if err != nil {
+		return err
+	}
+	return checkAllowedFields(GetEntityFields(new(T)), fields, c.createProtectedFields(), c.Options.CreatableFields)
+}
+
+// check the fields of update payload
+func (c *EntityController[T]) checkUpdateFields(fields []string) error {
+	return checkAllowedFields(GetEntityFields(new(T)), fields, c.updateProtectedFields(), c.Options.UpdatableFields)
+}
+
+// the bson fields of next which differ from original
+func changedFields(original interface{}, next interface{}) ([]string, error) {
+	originalM, err := entityToBsonM(original)
+	if err != nil {
+		return nil, err
+	}
+	nextM, err := entityToBsonM(next)
+	if err != nil {
+		return nil, err
+	}
+	fields := make([]string, 0)
+	for key, value := range nextM {
+		if originalValue, ok := originalM[key]; !ok || !reflect.DeepEqual(originalValue, value) {
+			fields = append(fields, key)
+		}
+	}
+	return fields, nil
+}
+
+// fields must not be protected,and must be in allowed list if it is not empty,
+// the json and bson names of a field are treated as the same field
+func checkAllowedFields(entityFields *EntityFields, fields []string, protectedFields map[string]struct{}, allowedFields []string) error {
+	var allowed map[string]struct{}
+	if len(allowedFields) > 0 {
+		allowed = make(map[string]struct{}, len(allowedFields))
+		for _, eachField := range allowedFields {
+			allowed[eachField] = struct{}{}
+		}
+	}
+	disallowedFields := make([]string, 0)
+	for _, eachField := range fields {
+		names := []string{eachField}
+		if entityField := entityFields.Get(eachField); entityField != nil {
+			names = append(names, entityField.Names()...)
+			if len(entityField.JsonName) > 0 {
+				// report by the name known by client
+				eachField = entityField.JsonName
+			}
+		}
+		if containsAnyKey(protectedFields, names) {
+			disallowedFields = append(disallowedFields, eachField)
+			continue
+		}
+		if allowed == nil {
+			continue
+		}
+		if !containsAnyKey(allowed, names) {
+			disallowedFields = append(disallowedFields, eachField)
+		}
+	}
+	if len(disallowedFields) > 0 {
+		sort.Strings(disallowedFields)
+		fieldErrors := make([]FieldError, 0, len(disallowedFields))
+		for _, eachField := range disallowedFields {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   eachField,
+				Code:    FieldErrorCodeNotAllowed,
+				Message: "field is not allowed",
+			})
+		}
+		return NewValidationError(fmt.Errorf("fields are not allowed:%s", strings.Join(disallowedFields, ",")), fieldErrors...)
+	}
+	return nil
+}
+
+func containsAnyKey(m map[string]struct{}, keys []string) bool {
+	for _, eachKey := range keys {
+		if _, ok := m[eachKey]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+func mapKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for eachKey := range m {
+		keys = append(keys, eachKey)
+	}
+	return keys
+}
+
+func newFieldNotAllowedError(field string) error {
+	return NewValidationError(fmt.Errorf("field is not allowed:%s", field), FieldError{
+		Field:   field,
+		Code:    FieldErrorCodeNotAllowed,
+		Message: "field is not allowed",
+	})
+}
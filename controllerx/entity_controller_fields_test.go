@@ -0,0 +1,91 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"testing"
+)
+
+type fieldsTestEntity struct {
+	Id        string `json:"id" bson:"_id,omitempty"`
+	Name      string `json:"name" bson:"name"`
+	IsDeleted bool   `json:"isDeleted" bson:"isDeleted"`
+	CreatorId string `json:"creatorId" bson:"creatorId"`
+	Version   int64  `json:"version" bson:"version"`
+}
+
+func TestChangedFields(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want []string
+	}{
+		{"empty", `{}`, []string{}},
+		{"zero value", `{"name":"","isDeleted":false}`, []string{}},
+		{"exact key", `{"name":"a"}`, []string{"name"}},
+		{"case insensitive key", `{"IsDeleted":true,"CREATORID":"u1"}`, []string{"creatorId", "isDeleted"}},
+		{"id", `{"ID":"x"}`, []string{"_id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			input := &fieldsTestEntity{}
+			if err := json.Unmarshal([]byte(tt.body), input); err != nil {
+				t.Fatal(err)
+			}
+			got, err := changedFields(&fieldsTestEntity{}, input)
+			if err != nil {
+				t.Fatal(err)
+			}
+			sort.Strings(got)
+			if len(got) != len(tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+			for i := range got {
+				if got[i] != tt.want[i] {
+					t.Fatalf("got %v,want %v", got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckAllowedFields(t *testing.T) {
+	entityFields := GetEntityFields(&fieldsTestEntity{})
+	protected := map[string]struct{}{"_id": {}, "id": {}, "creatorId": {}}
+	tests := []struct {
+		name       string
+		fields     []string
+		allowed    []string
+		disallowed []string
+	}{
+		{"no fields", nil, nil, nil},
+		{"not protected", []string{"name"}, nil, nil},
+		{"protected bson name reported by json name", []string{"_id", "creatorId"}, nil, []string{"creatorId", "id"}},
+		{"allowed list", []string{"name", "version"}, []string{"name"}, []string{"version"}},
+		{"protected wins over allowed", []string{"creatorId"}, []string{"creatorId"}, []string{"creatorId"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := checkAllowedFields(entityFields, tt.fields, protected, tt.allowed)
+			if len(tt.disallowed) <= 0 {
+				if err != nil {
+					t.Fatalf("unexpected error %v", err)
+				}
+				return
+			}
+			var httpErr *HttpError
+			if !errors.As(err, &httpErr) {
+				t.Fatalf("expected HttpError,got %v", err)
+			}
+			if len(httpErr.FieldErrors) != len(tt.disallowed) {
+				t.Fatalf("got %v,want %v", httpErr.FieldErrors, tt.disallowed)
+			}
+			for i, eachField := range tt.disallowed {
+				if httpErr.FieldErrors[i].Field != eachField {
+					t.Fatalf("got %v,want %v", httpErr.FieldErrors, tt.disallowed)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,105 @@
+package controllerx
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/abmpio/entity/filter"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// the filter compiler of controller,
+// field names of client are mapped to bson names of T and string values are converted to the field types of T
+func (c *EntityController[T]) filterCompiler() *FilterCompiler {
+	entityFields := GetEntityFields(new(T))
+	return &FilterCompiler{
+		Fields: c.Options.FilterableFields,
+		// the hidden fields can not be filtered,or their values could be guessed by the filter
+		DenyFunc:      c.isHiddenField,
+		FieldNameFunc: c.bsonFieldName,
+		ValueFunc: func(field string, op FilterOperator, value interface{}) (interface{}, error) {
+			switch op {
+			case FilterOperatorExists:
+				if s, ok := value.(string); ok {
+					return strconv.ParseBool(s)
+				}
+				return value, nil
+			case FilterOperatorContains, FilterOperatorStartsWith:
+				return value, nil
+			}
+			return CoerceFilterValue(entityFieldType(entityFields, field), value)
+		},
+	}
+}
+
+// map the field name of client to the bson name of T,the name is used as is if it is unknown
+func (c *EntityController[T]) bsonFieldName(field string) string {
+	name, path, _ := strings.Cut(field, ".")
+	entityField := GetEntityFields(new(T)).Get(name)
+	if entityField == nil || len(entityField.BsonName) <= 0 {
+		return field
+	}
+	if len(path) > 0 {
+		return entityField.BsonName + "." + path
+	}
+	return entityField.BsonName
+}
+
+// type of the field path,nil if it is unknown
+func entityFieldType(entityFields *EntityFields, field string) reflect.Type {
+	name, path, _ := strings.Cut(field, ".")
+	entityField := entityFields.Get(name)
+	if entityField == nil {
+		return nil
+	}
+	if len(path) <= 0 {
+		return entityField.Type
+	}
+	fieldType := entityField.Type
+	for fieldType.Kind() == reflect.Pointer || fieldType.Kind() == reflect.Slice {
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() != reflect.Struct {
+		return nil
+	}
+	return entityFieldType(GetEntityFields(reflect.New(fieldType).Interface()), path)
+}
+
+// compile the plain filter and where expression,
+// each key of plain filter is an eq condition and they are combined with where by and
+func (c *EntityController[T]) compileFilter(plain map[string]interface{}, where *FilterExpression) (bson.M, error) {
+	expression := &FilterExpression{
+		And: make([]*FilterExpression, 0, len(plain)+1),
+	}
+	for key, value := range plain {
+		expression.And = append(expression.And, &FilterExpression{
+			Field: key,
+			Op:    FilterOperatorEq,
+			Value: value,
+		})
+	}
+	if where != nil {
+		expression.And = append(expression.And, where)
+	}
+	if len(expression.And) <= 0 {
+		return bson.M{}, nil
+	}
+	return c.filterCompiler().Compile(expression)
+}
+
+// compile the filter of search input
+func (c *EntityController[T]) compileSearchFilter(input *SearchInput) (bson.M, error) {
+	return c.compileFilter(input.Filter, input.Where)
+}
+
+// compile the filter of query string,
+// both of the filter parameter and the bracket notation filter[field][op] are supported
+func (c *EntityController[T]) compileQueryFilter(ctx iris.Context) (bson.M, error) {
+	where, err := ParseQueryFilter(ctx.Request().URL.Query())
+	if err != nil {
+		return nil, err
+	}
+	return c.compileFilter(filter.MustGetFilterQuery(ctx.FormValue), where)
+}
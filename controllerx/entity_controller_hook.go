@@ -0,0 +1,123 @@
+package controllerx
+
+import (
+	"github.com/kataras/iris/v12"
+)
+
+// IEntityControllerHook is called by EntityController around its crud operations.
+// a hook can change the payload in place,abort the request by returning an error
+// (use *HttpError to control the status code) or enrich the response in AfterFind
+type IEntityControllerHook interface {
+	// entityValue is the *T decoded from request body
+	BeforeCreate(ctx iris.Context, entityValue interface{}) error
+	// entityValue is the created *T which will be written to response
+	AfterCreate(ctx iris.Context, entityValue interface{}) error
+	// updated is the field map which will be updated
+	BeforeUpdate(ctx iris.Context, id interface{}, updated map[string]interface{}) error
+	AfterUpdate(ctx iris.Context, id interface{}, updated map[string]interface{}) error
+	BeforeDelete(ctx iris.Context, ids []interface{}) error
+	AfterDelete(ctx iris.Context, ids []interface{}) error
+	// data is *T for GetById and []*T for All,GetList and Search,
+	// the returned value will be written to response
+	AfterFind(ctx iris.Context, data interface{}) (interface{}, error)
+}
+
+// EntityControllerHookBase implements IEntityControllerHook with no-op methods,
+// embed it to only override the needed methods
+type EntityControllerHookBase struct {
+}
+
+var _ IEntityControllerHook = (*EntityControllerHookBase)(nil)
+
+func (h *EntityControllerHookBase) BeforeCreate(ctx iris.Context, entityValue interface{}) error {
+	return nil
+}
+
+func (h *EntityControllerHookBase) AfterCreate(ctx iris.Context, entityValue interface{}) error {
+	return nil
+}
+
+func (h *EntityControllerHookBase) BeforeUpdate(ctx iris.Context, id interface{}, updated map[string]interface{}) error {
+	return nil
+}
+
+func (h *EntityControllerHookBase) AfterUpdate(ctx iris.Context, id interface{}, updated map[string]interface{}) error {
+	return nil
+}
+
+func (h *EntityControllerHookBase) BeforeDelete(ctx iris.Context, ids []interface{}) error {
+	return nil
+}
+
+func (h *EntityControllerHookBase) AfterDelete(ctx iris.Context, ids []interface{}) error {
+	return nil
+}
+
+func (h *EntityControllerHookBase) AfterFind(ctx iris.Context, data interface{}) (interface{}, error) {
+	return data, nil
+}
+
+func (c *EntityController[T]) runBeforeCreateHooks(ctx iris.Context, entityValue *T) error {
+	for _, eachHook := range c.Options.Hooks {
+		if err := eachHook.BeforeCreate(ctx, entityValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EntityController[T]) runAfterCreateHooks(ctx iris.Context, entityValue *T) error {
+	for _, eachHook := range c.Options.Hooks {
+		if err := eachHook.AfterCreate(ctx, entityValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EntityController[T]) runBeforeUpdateHooks(ctx iris.Context, id interface{}, updated map[string]interface{}) error {
+	for _, eachHook := range c.Options.Hooks {
+		if err := eachHook.BeforeUpdate(ctx, id, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EntityController[T]) runAfterUpdateHooks(ctx iris.Context, id interface{}, updated map[string]interface{}) error {
+	for _, eachHook := range c.Options.Hooks {
+		if err := eachHook.AfterUpdate(ctx, id, updated); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EntityController[T]) runBeforeDeleteHooks(ctx iris.Context, ids []interface{}) error {
+	for _, eachHook := range c.Options.Hooks {
+		if err := eachHook.BeforeDelete(ctx, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EntityController[T]) runAfterDeleteHooks(ctx iris.Context, ids []interface{}) error {
+	for _, eachHook := range c.Options.Hooks {
+		if err := eachHook.AfterDelete(ctx, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (c *EntityController[T]) runAfterFindHooks(ctx iris.Context, data interface{}) (interface{}, error) {
+	var err error
+	for _, eachHook := range c.Options.Hooks {
+		data, err = eachHook.AfterFind(ctx, data)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return data, nil
+}
@@ -0,0 +1,331 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+)
+
+// ImportMode decides what happens when some of rows are invalid
+type ImportMode string
+
+const (
+	// nothing is created if any row is invalid
+	ImportModeAllOrNothing ImportMode = "allOrNothing"
+	// the valid rows are created and the invalid rows are reported
+	ImportModeBestEffort ImportMode = "bestEffort"
+
+	// query parameter of import mode
+	ImportModeParamName = "mode"
+	// form file field of the uploaded file
+	ImportFileFieldName = "file"
+
+	// error code of the all-or-nothing import failed to create a row
+	ErrorCodeImportFailed = "import_failed"
+	// error code of the row whose created item can not be removed by the rollback
+	ErrorCodeImportRollbackFailed = "rollback_failed"
+)
+
+// ImportRowResult is the result of a row
+type ImportRowResult struct {
+	Row int `json:"row"`
+	// id of the created item
+	Id      string       `json:"id,omitempty"`
+	Error   string       `json:"error,omitempty"`
+	Code    string       `json:"code,omitempty"`
+	Details []FieldError `json:"details,omitempty"`
+}
+
+// ImportResult is the report of import
+type ImportResult struct {
+	Mode    ImportMode        `json:"mode"`
+	Total   int               `json:"total"`
+	Created int               `json:"created"`
+	Failed  int               `json:"failed"`
+	Rows    []ImportRowResult `json:"rows"`
+}
+
+// import the items of json array,ndjson or csv in body or the uploaded file,
+// the format is taken from format parameter,the file extension or the content type
+func (c *EntityController[T]) Import(ctx iris.Context) {
+	mode := ImportMode(ctx.URLParamDefault(ImportModeParamName, string(ImportModeAllOrNothing)))
+	if mode != ImportModeAllOrNothing && mode != ImportModeBestEffort {
+		HandleErrorBadRequest(ctx, fmt.Errorf("unsupported import mode:%s", mode))
+		return
+	}
+	format := ParseImportFormat(ctx.URLParam(ExportFormatParamName))
+	var reader io.Reader = ctx.Request().Body
+	if strings.HasPrefix(ctx.GetContentTypeRequested(), "multipart/") {
+		file, header, err := ctx.FormFile(ImportFileFieldName)
+		if err != nil {
+			HandleErrorBadRequest(ctx, err)
+			return
+		}
+		defer file.Close()
+		reader = file
+		if len(format) <= 0 {
+			format = ParseImportFormat(header.Filename)
+		}
+		if len(format) <= 0 {
+			format = ParseImportFormat(header.Header.Get("Content-Type"))
+		}
+	} else if len(format) <= 0 {
+		format = ParseImportFormat(ctx.GetContentTypeRequested())
+	}
+	if len(format) <= 0 {
+		HandleErrorBadRequest(ctx, fmt.Errorf("unknown import format,format must be json,ndjson or csv"))
+		return
+	}
+
+	rows, err := ReadImportRows(format, reader, c.Options.PaginationPolicy.allMaxSize(), c.convertCsvRow)
+	if err != nil {
+		var httpErr *HttpError
+		if errors.As(err, &httpErr) {
+			HandleError(ctx, err)
+			return
+		}
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	result, err := c.importRows(ctx, rows, mode)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccessWithData(ctx, result)
+}
+
+func (c *EntityController[T]) importRows(ctx iris.Context, rows []ImportRow, mode ImportMode) (*ImportResult, error) {
+	result := &ImportResult{
+		Mode:  mode,
+		Total: len(rows),
+		Rows:  make([]ImportRowResult, 0, len(rows)),
+	}
+	inputs := make([]*T, len(rows))
+	for i, eachRow := range rows {
+		err := eachRow.Err
+		if err == nil {
+			inputs[i], err = c.decodeCreate(eachRow.Data)
+		}
+		if err != nil {
+			result.Rows = append(result.Rows, importRowError(eachRow.Row, err))
+		}
+	}
+	if mode == ImportModeAllOrNothing && len(result.Rows) > 0 {
+		result.Failed = len(result.Rows)
+		return nil, importValidationError(result)
+	}
+	// the before create hooks run only when the import goes ahead,
+	// so they never see the rows of the import rejected by validation
+	for i, eachRow := range rows {
+		if inputs[i] == nil {
+			continue
+		}
+		err := c.beforeCreate(ctx, inputs[i])
+		if err != nil && mode == ImportModeAllOrNothing {
+			result.Rows = append(result.Rows, importRowError(eachRow.Row, err))
+			result.Failed = result.Total
+			status, _, _ := GetErrorDetails(err)
+			return nil, importFailedError(result, status)
+		}
+		if err != nil {
+			inputs[i] = nil
+			result.Rows = append(result.Rows, importRowError(eachRow.Row, err))
+		}
+	}
+
+	service := c.GetEntityService()
+	createdIds := make([]interface{}, 0, len(rows))
+	createdItems := make([]*T, 0, len(rows))
+	createdRows := make([]int, 0, len(rows))
+	for i, eachRow := range rows {
+		if inputs[i] == nil {
+			continue
+		}
+		newItem, err := service.Create(inputs[i])
+		var id interface{}
+		if err == nil {
+			var m map[string]interface{}
+			if m, err = entityToBsonM(newItem); err == nil {
+				id = m["_id"]
+			}
+		}
+		if err != nil && mode == ImportModeAllOrNothing {
+			// remove the created items,mongo transaction requires replica set so it is not used,
+			// the report has the failed row and the rows failed to be rolled back
+			result.Rows = c.rollbackImportedItems(createdRows, createdIds)
+			result.Rows = append(result.Rows, importRowError(eachRow.Row, err))
+			result.Failed = result.Total
+			status, _, _ := GetErrorDetails(err)
+			return nil, importFailedError(result, status)
+		}
+		if err != nil {
+			result.Rows = append(result.Rows, importRowError(eachRow.Row, err))
+			continue
+		}
+		createdIds = append(createdIds, id)
+		createdItems = append(createdItems, newItem)
+		createdRows = append(createdRows, eachRow.Row)
+		result.Rows = append(result.Rows, ImportRowResult{
+			Row: eachRow.Row,
+			Id:  c.idCodec().Format(id),
+		})
+	}
+	// the side effects start after the items are kept,so nothing needs to be undone by the rollback
+	c.afterCreate(ctx, createdItems...)
+	result.Created = len(createdIds)
+	result.Failed = result.Total - result.Created
+	sort.SliceStable(result.Rows, func(i, j int) bool {
+		return result.Rows[i].Row < result.Rows[j].Row
+	})
+	return result, nil
+}
+
+// remove the items created by the failed all-or-nothing import,
+// they are removed directly because neither the after create hooks nor the audit have seen them,
+// the items failed to be removed are reported
+func (c *EntityController[T]) rollbackImportedItems(rows []int, ids []interface{}) []ImportRowResult {
+	failed := make([]ImportRowResult, 0)
+	for i, eachId := range ids {
+		if err := c.deleteById(eachId); err != nil {
+			failed = append(failed, ImportRowResult{
+				Row:   rows[i],
+				Id:    c.idCodec().Format(eachId),
+				Error: fmt.Sprintf("the created item can not be rolled back,%s", err.Error()),
+				Code:  ErrorCodeImportRollbackFailed,
+			})
+		}
+	}
+	return failed
+}
+
+// convert csv record to json object,headers are the export headers or the json fields,
+// the values are converted to the field types of T and the empty values are omitted
+func (c *EntityController[T]) convertCsvRow(headers []string, record []string) (map[string]interface{}, error) {
+	fieldOfHeader := make(map[string]string)
+	for _, eachColumn := range c.exportColumns() {
+		fieldOfHeader[eachColumn.Header] = eachColumn.Field
+	}
+	entityFields := GetEntityFields(new(T))
+	m := make(map[string]interface{})
+	for i, eachValue := range record {
+		if i >= len(headers) || len(eachValue) <= 0 {
+			continue
+		}
+		field := strings.TrimSpace(headers[i])
+		if mapped, ok := fieldOfHeader[field]; ok {
+			field = mapped
+		}
+		if len(field) <= 0 {
+			continue
+		}
+		value, err := convertCsvValue(entityFieldType(entityFields, field), eachValue)
+		if err != nil {
+			return nil, NewValidationError(fmt.Errorf("invalid value of field %s,%s", field, err.Error()), FieldError{
+				Field:   field,
+				Code:    "invalid_value",
+				Message: err.Error(),
+			})
+		}
+		setJsonPath(m, strings.Split(field, "."), value)
+	}
+	return m, nil
+}
+
+// convert csv cell to the value of fieldType,struct,map and slice values are written as json
+func convertCsvValue(fieldType reflect.Type, value string) (interface{}, error) {
+	if fieldType != nil {
+		t := fieldType
+		for t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+		switch t.Kind() {
+		case reflect.Struct, reflect.Map, reflect.Slice, reflect.Array:
+			trimmed := strings.TrimSpace(value)
+			if strings.HasPrefix(trimmed, "{") || strings.HasPrefix(trimmed, "[") {
+				var v interface{}
+				if err := json.Unmarshal([]byte(trimmed), &v); err != nil {
+					return nil, err
+				}
+				return v, nil
+			}
+		}
+	}
+	return CoerceFilterValue(fieldType, value)
+}
+
+func setJsonPath(m map[string]interface{}, path []string, value interface{}) {
+	if len(path) == 1 {
+		m[path[0]] = value
+		return
+	}
+	child, ok := m[path[0]].(map[string]interface{})
+	if !ok {
+		child = make(map[string]interface{})
+		m[path[0]] = child
+	}
+	setJsonPath(child, path[1:], value)
+}
+
+func importRowError(row int, err error) ImportRowResult {
+	_, code, fieldErrors := GetErrorDetails(err)
+	return ImportRowResult{
+		Row:     row,
+		Error:   err.Error(),
+		Code:    code,
+		Details: fieldErrors,
+	}
+}
+
+// the error of all-or-nothing import with invalid rows
+func importValidationError(result *ImportResult) *HttpError {
+	return NewValidationError(fmt.Errorf("%d of %d rows are invalid,nothing is imported", result.Failed, result.Total), importFieldErrors(result)...)
+}
+
+// the error of all-or-nothing import failed to create a row,
+// it is 500 if some of the created items can not be rolled back,the status of the row error otherwise
+func importFailedError(result *ImportResult, statusCode int) *HttpError {
+	message := "import failed,nothing is imported"
+	for _, eachRow := range result.Rows {
+		if eachRow.Code == ErrorCodeImportRollbackFailed {
+			statusCode = iris.StatusInternalServerError
+			message = "import failed,some of the created items can not be rolled back"
+			break
+		}
+	}
+	httpErr := NewValidationError(errors.New(message), importFieldErrors(result)...)
+	httpErr.StatusCode = statusCode
+	httpErr.Code = ErrorCodeImportFailed
+	return httpErr
+}
+
+// the row results as field errors,the fields are prefixed with the row
+func importFieldErrors(result *ImportResult) []FieldError {
+	fieldErrors := make([]FieldError, 0)
+	for _, eachRow := range result.Rows {
+		prefix := fmt.Sprintf("rows[%d]", eachRow.Row)
+		if len(eachRow.Details) <= 0 {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   prefix,
+				Code:    eachRow.Code,
+				Message: eachRow.Error,
+			})
+			continue
+		}
+		for _, eachDetail := range eachRow.Details {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   prefix + "." + eachDetail.Field,
+				Code:    eachDetail.Code,
+				Message: eachDetail.Message,
+			})
+		}
+	}
+	return fieldErrors
+}
@@ -0,0 +1,108 @@
+package controllerx
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestConvertCsvValue(t *testing.T) {
+	type profile struct {
+		City string `json:"city"`
+	}
+	tests := []struct {
+		name      string
+		fieldType reflect.Type
+		value     string
+		want      interface{}
+		wantErr   bool
+	}{
+		{"unknown field", nil, "1", "1", false},
+		{"int", reflect.TypeOf(0), "1", int64(1), false},
+		{"invalid int", reflect.TypeOf(0), "a", nil, true},
+		{"struct as json", reflect.TypeOf(profile{}), ` {"city":"x"}`, map[string]interface{}{"city": "x"}, false},
+		{"slice as json", reflect.TypeOf([]string{}), `["a","b"]`, []interface{}{"a", "b"}, false},
+		{"slice as single value", reflect.TypeOf([]int{}), `3`, int64(3), false},
+		{"invalid json", reflect.TypeOf(&profile{}), `{"city":`, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := convertCsvValue(tt.fieldType, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v,want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSetJsonPath(t *testing.T) {
+	m := map[string]interface{}{"a": "x"}
+	setJsonPath(m, []string{"b", "c"}, 1)
+	setJsonPath(m, []string{"b", "d"}, 2)
+	setJsonPath(m, []string{"a", "e"}, 3)
+	want := map[string]interface{}{
+		"a": map[string]interface{}{"e": 3},
+		"b": map[string]interface{}{"c": 1, "d": 2},
+	}
+	if !reflect.DeepEqual(m, want) {
+		t.Fatalf("got %v,want %v", m, want)
+	}
+}
+
+func TestImportFailedError(t *testing.T) {
+	tests := []struct {
+		name       string
+		rows       []ImportRowResult
+		status     int
+		wantStatus int
+		wantFields []string
+	}{
+		{
+			"row failed",
+			[]ImportRowResult{{Row: 3, Error: "duplicated", Code: "conflict"}},
+			409,
+			409,
+			[]string{"rows[3]"},
+		},
+		{
+			"row failed with details",
+			[]ImportRowResult{{Row: 2, Error: "invalid", Details: []FieldError{{Field: "name", Code: "required"}}}},
+			400,
+			400,
+			[]string{"rows[2].name"},
+		},
+		{
+			"rollback failed",
+			[]ImportRowResult{
+				{Row: 1, Id: "a", Error: "can not be rolled back", Code: ErrorCodeImportRollbackFailed},
+				{Row: 2, Error: "duplicated", Code: "conflict"},
+			},
+			409,
+			500,
+			[]string{"rows[1]", "rows[2]"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := importFailedError(&ImportResult{Mode: ImportModeAllOrNothing, Total: 3, Failed: 3, Rows: tt.rows}, tt.status)
+			if err.StatusCode != tt.wantStatus || err.Code != ErrorCodeImportFailed {
+				t.Fatalf("got status %d,code %s", err.StatusCode, err.Code)
+			}
+			fields := make([]string, 0, len(err.FieldErrors))
+			for _, eachFieldError := range err.FieldErrors {
+				fields = append(fields, eachFieldError.Field)
+			}
+			if !reflect.DeepEqual(fields, tt.wantFields) {
+				t.Fatalf("got fields %v,want %v", fields, tt.wantFields)
+			}
+		})
+	}
+}
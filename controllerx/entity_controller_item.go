@@ -0,0 +1,94 @@
+package controllerx
+
+import (
+	"errors"
+
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// the codec of entity id,ObjectIdCodec by default
+func (c *EntityController[T]) idCodec() IIdCodec {
+	if c.Options.IdCodec == nil {
+		return ObjectIdCodec{}
+	}
+	return c.Options.IdCodec
+}
+
+// find the item of the id route parameter,
+// the missing item and the soft deleted item(unless includeDeleted) are reported as 404
+func (c *EntityController[T]) findItemByIdParam(ctx iris.Context, includeDeleted bool) (interface{}, *T, error) {
+	idValue := ctx.Params().Get("id")
+	if len(idValue) <= 0 {
+		return nil, nil, NewHttpError(iris.StatusBadRequest, errors.New("id must not be empty"))
+	}
+	id, err := c.idCodec().Parse(idValue)
+	if err != nil {
+		return nil, nil, NewHttpError(iris.StatusBadRequest, err)
+	}
+	item, err := c.findById(id)
+	if err != nil {
+		if IsNotFoundError(err) {
+			return nil, nil, NewNotFoundError(idValue)
+		}
+		return nil, nil, err
+	}
+	if item == nil || (!includeDeleted && c.isSoftDeleted(item)) {
+		return nil, nil, NewNotFoundError(idValue)
+	}
+	return id, item, nil
+}
+
+// check the item belongs to current user,the item of other users is reported as not found
+// so that the ids of other users can not be probed
+func (c *EntityController[T]) checkOwnerOfItem(ctx iris.Context, id interface{}, item *T) error {
+	if !c.isOwnedByCurrentUser(ctx, item) {
+		return NewNotFoundError(c.idCodec().Format(id))
+	}
+	return nil
+}
+
+// find item by _id,the entity service is used directly for ObjectID
+func (c *EntityController[T]) findById(id interface{}) (*T, error) {
+	service := c.GetEntityService()
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return service.FindById(oid)
+	}
+	list, err := service.FindList(bson.M{"_id": id})
+	if err != nil {
+		return nil, err
+	}
+	if len(list) <= 0 {
+		return nil, nil
+	}
+	return list[0], nil
+}
+
+// set fields of the item by _id
+func (c *EntityController[T]) updateFieldsById(ctx iris.Context, id interface{}, updated map[string]interface{}) error {
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return c.GetEntityService().UpdateFields(oid, updated)
+	}
+	collection, err := c.GetCollection()
+	if err != nil {
+		return err
+	}
+	result, err := collection.UpdateOne(ctx.Request().Context(), bson.M{"_id": id}, bson.M{"$set": updated})
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount <= 0 {
+		return NewNotFoundError(c.idCodec().Format(id))
+	}
+	return nil
+}
+
+// delete the item by _id
+func (c *EntityController[T]) deleteById(id interface{}) error {
+	if oid, ok := id.(primitive.ObjectID); ok {
+		return c.GetEntityService().Delete(oid)
+	}
+	_, err := c.GetEntityService().DeleteMany(bson.M{"_id": id})
+	return err
+}
@@ -0,0 +1,61 @@
+package controllerx
+
+import (
+	"github.com/abmpio/irisx/casdoor"
+	"github.com/kataras/iris/v12"
+)
+
+// owner scoped mode only takes effect when T implements entity.IEntityWithUser
+func (c *EntityController[T]) isOwnerScoped() bool {
+	if !c.Options.OwnerScoped {
+		return false
+	}
+	return checkEntityIsIEntityWithUser(new(T)) != nil
+}
+
+// add creatorId filter to filter in owner scoped mode
+func (c *EntityController[T]) applyOwnerFilter(ctx iris.Context, filter map[string]interface{}) error {
+	if !c.isOwnerScoped() {
+		return nil
+	}
+	userId := GetUserId(ctx)
+	if userId == "" {
+		return NewHttpError(iris.StatusUnauthorized, casdoor.ErrTokenMissing)
+	}
+	filter["creatorId"] = userId
+	return nil
+}
+
+// check item is owned by current user in owner scoped mode
+func (c *EntityController[T]) isOwnedByCurrentUser(ctx iris.Context, item *T) bool {
+	if !c.isOwnerScoped() {
+		return true
+	}
+	return FilterMustIsCurrentUserId(item, ctx)
+}
+
+// check all of ids are owned by current user in owner scoped mode
+func (c *EntityController[T]) checkOwnerOfIds(ctx iris.Context, ids []interface{}) error {
+	if !c.isOwnerScoped() {
+		return nil
+	}
+	filter := map[string]interface{}{
+		"_id": map[string]interface{}{"$in": ids},
+	}
+	if err := c.applyOwnerFilter(ctx, filter); err != nil {
+		return err
+	}
+	count, err := c.GetEntityService().Count(filter)
+	if err != nil {
+		return err
+	}
+	uniqueIds := make(map[interface{}]struct{}, len(ids))
+	for _, eachId := range ids {
+		uniqueIds[eachId] = struct{}{}
+	}
+	if count != int64(len(uniqueIds)) {
+		// the missing items and the items of other users are not distinguished
+		return NewHttpErrorf(iris.StatusNotFound, "some of items are not found")
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package controllerx
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	SoftDeleteFieldIsDeleted    = "isDeleted"
+	SoftDeleteFieldDeletionTime = "deletionTime"
+	SoftDeleteFieldDeleterId    = "deleterId"
+
+	// query parameter or search input field used to include the deleted items
+	IncludeDeletedParamName = "includeDeleted"
+)
+
+// exclude the deleted items if soft delete is enabled
+func (c *EntityController[T]) applySoftDeleteFilter(filter map[string]interface{}, includeDeleted bool) {
+	if !c.Options.SoftDeleteEnabled || includeDeleted {
+		return
+	}
+	exclusion := bson.M{"$ne": true}
+	clientCondition, ok := filter[SoftDeleteFieldIsDeleted]
+	if !ok {
+		filter[SoftDeleteFieldIsDeleted] = exclusion
+		return
+	}
+	if c.Options.SoftDeleteFilterAllowed {
+		// the isDeleted condition of client replaces the exclusion
+		return
+	}
+	// keep the condition of client but never return the deleted items
+	delete(filter, SoftDeleteFieldIsDeleted)
+	clauses := bson.A{
+		bson.M{SoftDeleteFieldIsDeleted: clientCondition},
+		bson.M{SoftDeleteFieldIsDeleted: exclusion},
+	}
+	if and, ok := filter["$and"]; ok {
+		clauses = append(clauses, bson.M{"$and": and})
+	}
+	filter["$and"] = clauses
+}
+
+// is the item soft deleted
+func (c *EntityController[T]) isSoftDeleted(item *T) bool {
+	if !c.Options.SoftDeleteEnabled || item == nil {
+		return false
+	}
+	m, err := entityToBsonM(item)
+	if err != nil {
+		return false
+	}
+	isDeleted, _ := m[SoftDeleteFieldIsDeleted].(bool)
+	return isDeleted
+}
+
+// fields to mark item as deleted
+func (c *EntityController[T]) softDeleteFields(ctx iris.Context) map[string]interface{} {
+	now := time.Now()
+	updated := map[string]interface{}{
+		SoftDeleteFieldIsDeleted:    true,
+		SoftDeleteFieldDeletionTime: &now,
+	}
+	userId := GetUserId(ctx)
+	if userId != "" {
+		updated[SoftDeleteFieldDeleterId] = userId
+	}
+	return updated
+}
+
+// mark items as deleted by one request,the deleted items keep their deletion time and deleter
+func (c *EntityController[T]) softDelete(ctx iris.Context, ids []interface{}) error {
+	collection, err := c.GetCollection()
+	if err != nil {
+		return err
+	}
+	filter := bson.M{
+		"_id":                    bson.M{"$in": ids},
+		SoftDeleteFieldIsDeleted: bson.M{"$ne": true},
+	}
+	_, err = collection.UpdateMany(ctx.Request().Context(), filter, bson.M{"$set": c.softDeleteFields(ctx)})
+	return err
+}
+
+// restore soft deleted item
+func (c *EntityController[T]) Restore(ctx iris.Context) {
+	id, item, err := c.findItemByIdParam(ctx, true)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.checkOwnerOfItem(ctx, id, item); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if !c.isSoftDeleted(item) {
+		HandleErrorBadRequest(ctx, fmt.Errorf("item is not deleted,id:%s", c.idCodec().Format(id)))
+		return
+	}
+
+	updated := map[string]interface{}{
+		SoftDeleteFieldIsDeleted:    false,
+		SoftDeleteFieldDeletionTime: nil,
+		SoftDeleteFieldDeleterId:    "",
+	}
+	if err = c.runBeforeUpdateHooks(ctx, id, updated); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	changes := c.auditUpdateChanges(item, updated, nil)
+	c.hookUpdate(ctx, updated)
+	err = c.updateFieldsById(ctx, id, updated)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	c.writeAudit(ctx, c.newAuditRecord(ctx, AuditActionRestore, id, changes))
+	if err = c.runAfterUpdateHooks(ctx, id, updated); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccess(ctx)
+}
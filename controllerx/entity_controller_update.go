@@ -0,0 +1,399 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"github.com/abmpio/mongodbr"
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// update,replace the whole document with the payload,
+// the protected fields which are not in payload keep their values
+func (c *EntityController[T]) Update(ctx iris.Context) {
+	id, item, versionFilter, err := c.getItemForUpdate(ctx)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	body, err := ctx.GetBody()
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	if err = c.replaceItem(ctx, id, item, versionFilter, body); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccess(ctx)
+}
+
+// replace item with the payload,the protected fields which are not in payload keep their values
+func (c *EntityController[T]) replaceItem(ctx iris.Context, id interface{}, item *T, versionFilter bson.M, body []byte) error {
+	fields := make(map[string]interface{})
+	err := json.Unmarshal(body, &fields)
+	if err != nil {
+		return NewHttpError(iris.StatusBadRequest, err)
+	}
+	input := new(T)
+	err = json.Unmarshal(body, input)
+	if err != nil {
+		return NewHttpError(iris.StatusBadRequest, err)
+	}
+	err = mongodbr.Validate(input)
+	if err != nil {
+		return NewHttpError(iris.StatusBadRequest, err)
+	}
+
+	set, unset, err := c.diffEntity(item, input, fields)
+	if err != nil {
+		return err
+	}
+	if err = c.checkUpdateFields(append(mapKeys(set), mapKeys(unset)...)); err != nil {
+		return err
+	}
+	return c.saveUpdate(ctx, id, item, versionFilter, set, unset)
+}
+
+// patch,the payload is json merge patch(application/merge-patch+json or application/json)
+// or json patch(application/json-patch+json)
+func (c *EntityController[T]) Patch(ctx iris.Context) {
+	id, item, versionFilter, err := c.getItemForUpdate(ctx)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	body, err := ctx.GetBody()
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	patched, err := c.applyPatch(ctx.GetContentTypeRequested(), item, body)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+
+	set, unset, err := c.diffEntity(item, patched, nil)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	if err = c.checkUpdateFields(append(mapKeys(set), mapKeys(unset)...)); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.validatePartialEntity(patched, append(mapKeys(set), mapKeys(unset)...)); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if err = c.saveUpdate(ctx, id, item, versionFilter, set, unset); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccess(ctx)
+}
+
+// find the item to be updated,check the ownership and If-Match header
+func (c *EntityController[T]) getItemForUpdate(ctx iris.Context) (interface{}, *T, bson.M, error) {
+	id, item, err := c.findItemByIdParam(ctx, false)
+	if err != nil {
+		return nil, nil, nil, err
+	}
+	if err = c.checkOwnerOfItem(ctx, id, item); err != nil {
+		return nil, nil, nil, err
+	}
+	var versionFilter bson.M
+	if c.Options.ConcurrencyControlEnabled {
+		versionFilter, err = c.checkIfMatch(ctx, item)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+	}
+	return id, item, versionFilter, nil
+}
+
+// apply the patch body to item and return the patched copy,
+// the hidden fields are removed from the document before patching and can not be referenced by the patch
+func (c *EntityController[T]) applyPatch(contentType string, item *T, body []byte) (*T, error) {
+	data, err := json.Marshal(item)
+	if err != nil {
+		return nil, err
+	}
+	var doc interface{}
+	if err = json.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc = c.fieldProjection(nil).applyJsonValue(doc)
+	switch contentType {
+	case ContentTypeJsonPatch:
+		operations := make([]JsonPatchOperation, 0)
+		if err = json.Unmarshal(body, &operations); err != nil {
+			return nil, NewHttpError(iris.StatusBadRequest, err)
+		}
+		for _, eachOperation := range operations {
+			if err = c.checkPatchOperation(eachOperation); err != nil {
+				return nil, err
+			}
+		}
+		doc, err = ApplyJsonPatch(doc, operations)
+		if err != nil {
+			return nil, NewHttpError(iris.StatusBadRequest, err)
+		}
+	default:
+		var patch interface{}
+		if err = json.Unmarshal(body, &patch); err != nil {
+			return nil, NewHttpError(iris.StatusBadRequest, err)
+		}
+		if err = c.checkMergePatch(patch, ""); err != nil {
+			return nil, err
+		}
+		doc = ApplyMergePatch(doc, patch)
+	}
+	if _, ok := doc.(map[string]interface{}); !ok {
+		return nil, NewHttpErrorf(iris.StatusBadRequest, "patched document must be an object")
+	}
+	data, err = json.Marshal(doc)
+	if err != nil {
+		return nil, err
+	}
+	patched := new(T)
+	if err = json.Unmarshal(data, patched); err != nil {
+		return nil, NewHttpError(iris.StatusBadRequest, err)
+	}
+	return patched, nil
+}
+
+// the paths read by the operation must not be hidden,the paths written must be updatable too
+func (c *EntityController[T]) checkPatchOperation(operation JsonPatchOperation) error {
+	switch operation.Op {
+	case "test":
+		return c.checkPatchPath(operation.Path, false)
+	case "copy":
+		if err := c.checkPatchPath(operation.From, false); err != nil {
+			return err
+		}
+	case "move":
+		if err := c.checkPatchPath(operation.From, true); err != nil {
+			return err
+		}
+	}
+	return c.checkPatchPath(operation.Path, true)
+}
+
+func (c *EntityController[T]) checkPatchPath(pointer string, write bool) error {
+	tokens, err := parseJsonPointer(pointer)
+	if err != nil {
+		return NewHttpError(iris.StatusBadRequest, err)
+	}
+	if len(tokens) <= 0 {
+		if write {
+			return NewHttpErrorf(iris.StatusBadRequest, "the whole document can not be replaced by patch")
+		}
+		return nil
+	}
+	field := strings.Join(tokens, ".")
+	if c.isHiddenField(field) {
+		return newFieldNotAllowedError(field)
+	}
+	if write {
+		return c.checkUpdateFields([]string{tokens[0]})
+	}
+	return nil
+}
+
+// the fields of merge patch must not be hidden
+func (c *EntityController[T]) checkMergePatch(patch interface{}, parent string) error {
+	object, ok := patch.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	for key, value := range object {
+		field := key
+		if len(parent) > 0 {
+			field = parent + "." + key
+		}
+		if c.isHiddenField(field) {
+			return newFieldNotAllowedError(field)
+		}
+		if err := c.checkMergePatch(value, field); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// compute $set and $unset from original to next,
+// the fields not serialized to json and the fields managed by server keep their values,
+// so are the protected fields not in provided when provided is not nil
+func (c *EntityController[T]) diffEntity(original *T, next *T, provided map[string]interface{}) (bson.M, bson.M, error) {
+	originalM, err := entityToBsonM(original)
+	if err != nil {
+		return nil, nil, err
+	}
+	nextM, err := entityToBsonM(next)
+	if err != nil {
+		return nil, nil, err
+	}
+	c.keepHiddenPaths(originalM, nextM)
+	set, unset := diffBsonM(GetEntityFields(new(T)), originalM, nextM, c.updateProtectedFields(), c.serverManagedFields(), provided)
+	return set, unset, nil
+}
+
+// the nested hidden fields keep their original values,the top level ones are kept as managed fields
+func (c *EntityController[T]) keepHiddenPaths(originalM bson.M, nextM bson.M) {
+	for _, eachField := range c.Options.HiddenFields {
+		path := strings.Split(c.bsonFieldName(eachField), ".")
+		if len(path) <= 1 {
+			continue
+		}
+		parent, ok := bsonPathGet(nextM, path[:len(path)-1])
+		if !ok {
+			continue
+		}
+		parentM, ok := parent.(bson.M)
+		if !ok {
+			continue
+		}
+		if value, ok := bsonPathGet(originalM, path); ok {
+			parentM[path[len(path)-1]] = value
+		} else {
+			delete(parentM, path[len(path)-1])
+		}
+	}
+}
+
+// the fields always keeping their values on update,
+// the hidden fields are kept too because clients never receive them
+func (c *EntityController[T]) serverManagedFields() map[string]struct{} {
+	fields := make(map[string]struct{}, len(ServerManagedFields)+len(c.Options.HiddenFields)+1)
+	for _, eachField := range ServerManagedFields {
+		fields[eachField] = struct{}{}
+	}
+	if len(c.Options.VersionField) > 0 {
+		fields[c.Options.VersionField] = struct{}{}
+	}
+	for _, eachField := range c.Options.HiddenFields {
+		fields[c.bsonFieldName(eachField)] = struct{}{}
+	}
+	return fields
+}
+
+func diffBsonM(entityFields *EntityFields,
+	originalM bson.M,
+	nextM bson.M,
+	protectedFields map[string]struct{},
+	managedFields map[string]struct{},
+	provided map[string]interface{}) (bson.M, bson.M) {
+	for key, value := range originalM {
+		field := entityFields.Get(key)
+		if field == nil || len(field.JsonName) <= 0 {
+			nextM[key] = value
+			continue
+		}
+		if containsAnyKey(managedFields, field.Names()) {
+			nextM[key] = value
+			continue
+		}
+		if provided == nil {
+			continue
+		}
+		if _, ok := protectedFields[key]; ok && !isAnyKeyProvided(provided, field.Names()) {
+			nextM[key] = value
+		}
+	}
+	for key := range nextM {
+		if _, ok := originalM[key]; ok {
+			continue
+		}
+		// the managed fields missing in original are not set by client either
+		names := []string{key}
+		if field := entityFields.Get(key); field != nil {
+			names = field.Names()
+		}
+		if containsAnyKey(managedFields, names) {
+			delete(nextM, key)
+		}
+	}
+
+	set := bson.M{}
+	unset := bson.M{}
+	for key, value := range nextM {
+		originalValue, ok := originalM[key]
+		if !ok || !reflect.DeepEqual(originalValue, value) {
+			set[key] = value
+		}
+	}
+	for key := range originalM {
+		if _, ok := nextM[key]; !ok {
+			unset[key] = ""
+		}
+	}
+	return set, unset
+}
+
+func isAnyKeyProvided(provided map[string]interface{}, keys []string) bool {
+	for _, eachKey := range keys {
+		if _, ok := provided[eachKey]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// run the update hooks and save the changes of item
+func (c *EntityController[T]) saveUpdate(ctx iris.Context, id interface{}, item *T, versionFilter bson.M, set bson.M, unset bson.M) error {
+	if len(set) <= 0 && len(unset) <= 0 {
+		return nil
+	}
+	if err := c.runBeforeUpdateHooks(ctx, id, set); err != nil {
+		return err
+	}
+	// the changes are taken before the modification time is stamped
+	changes := c.auditUpdateChanges(item, set, unset)
+	c.hookUpdate(ctx, set)
+	if err := c.updateItem(ctx, id, versionFilter, set, unset); err != nil {
+		return err
+	}
+	c.writeAudit(ctx, c.newAuditRecord(ctx, AuditActionUpdate, id, changes))
+	return c.runAfterUpdateHooks(ctx, id, set)
+}
+
+// update the item,with the concurrency check if versionFilter is not empty
+func (c *EntityController[T]) updateItem(ctx iris.Context, id interface{}, versionFilter bson.M, set map[string]interface{}, unset map[string]interface{}) error {
+	if !c.Options.ConcurrencyControlEnabled && len(unset) <= 0 {
+		return c.updateFieldsById(ctx, id, set)
+	}
+	collection, err := c.GetCollection()
+	if err != nil {
+		return err
+	}
+	filter := bson.M{"_id": id}
+	for key, value := range versionFilter {
+		filter[key] = value
+	}
+	update := bson.M{}
+	if len(set) > 0 {
+		update["$set"] = set
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+	if c.Options.ConcurrencyControlEnabled && len(c.Options.VersionField) > 0 {
+		update["$inc"] = bson.M{c.Options.VersionField: 1}
+	}
+	result, err := collection.UpdateOne(ctx.Request().Context(), filter, update)
+	if err != nil {
+		return err
+	}
+	if result.MatchedCount <= 0 {
+		if c.Options.ConcurrencyControlEnabled {
+			return ErrPreconditionFailed
+		}
+		return NewNotFoundError(c.idCodec().Format(id))
+	}
+	return nil
+}
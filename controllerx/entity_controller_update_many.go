@@ -0,0 +1,230 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+
+	"github.com/abmpio/mongodbr"
+	"github.com/abmpio/webserver/controller"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type UpdateManyInput struct {
+	// equal conditions,combined with Where by and
+	Filter map[string]interface{} `json:"filter"`
+	// conditions combined with Filter by and,the fields must be filterable by controller
+	Where *FilterExpression `json:"where"`
+	// the fields to set,keyed by the json fields of entity
+	Set map[string]interface{} `json:"set"`
+}
+
+type UpdateManyResult struct {
+	Matched  int64 `json:"matched"`
+	Modified int64 `json:"modified"`
+}
+
+// update many,set the fields of the items matching the filter,
+// the filter must not be empty and the number of matched items must not exceed the max size of all
+func (c *EntityController[T]) UpdateMany(ctx iris.Context) {
+	input := &UpdateManyInput{}
+	err := ctx.ReadJSON(input)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	err = mongodbr.Validate(input)
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	if len(input.Set) <= 0 {
+		HandleErrorBadRequest(ctx, fmt.Errorf("set must not be empty"))
+		return
+	}
+	query, err := c.compileFilter(input.Filter, input.Where)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	if len(query) <= 0 {
+		HandleErrorBadRequest(ctx, fmt.Errorf("filter must not be empty"))
+		return
+	}
+	if err = c.applyOwnerFilter(ctx, query); err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	c.applySoftDeleteFilter(query, false)
+	set, err := c.updateManyFields(input.Set)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	result, err := c.updateMany(ctx, query, set)
+	if err != nil {
+		HandleError(ctx, err)
+		return
+	}
+	controller.HandleSuccessWithData(ctx, result)
+}
+
+// check and convert the fields to the bson fields of T
+func (c *EntityController[T]) updateManyFields(fields map[string]interface{}) (bson.M, error) {
+	if err := c.checkUpdateFields(mapKeys(fields)); err != nil {
+		return nil, err
+	}
+	entityFields := GetEntityFields(new(T))
+	for key := range fields {
+		if field := entityFields.Get(key); field == nil || len(field.BsonName) <= 0 {
+			return nil, NewValidationError(fmt.Errorf("unknown field %s", key), FieldError{
+				Field:   key,
+				Code:    FieldErrorCodeNotAllowed,
+				Message: "unknown field",
+			})
+		}
+	}
+	// decode to T so that the values have the field types
+	data, err := json.Marshal(fields)
+	if err != nil {
+		return nil, NewHttpError(iris.StatusBadRequest, err)
+	}
+	input := new(T)
+	if err = json.Unmarshal(data, input); err != nil {
+		return nil, NewHttpError(iris.StatusBadRequest, err)
+	}
+	if err = c.validatePartialEntity(input, mapKeys(fields)); err != nil {
+		return nil, err
+	}
+	m, err := entityToBsonM(input)
+	if err != nil {
+		return nil, err
+	}
+	set := bson.M{}
+	for key := range fields {
+		field := entityFields.Get(key)
+		value, ok := m[field.BsonName]
+		if !ok {
+			// omitted by omitempty
+			value = reflect.Zero(field.Type).Interface()
+		}
+		set[field.BsonName] = value
+	}
+	return set, nil
+}
+
+// find the items matching query,run the update hooks of each item and update them,
+// each before update hook gets its own copy of set and the changed copy is used to update and audit that item,
+// the items with the same set are updated by one request
+func (c *EntityController[T]) updateMany(ctx iris.Context, query bson.M, set bson.M) (*UpdateManyResult, error) {
+	collection, err := c.GetCollection()
+	if err != nil {
+		return nil, err
+	}
+	// the whole items are loaded for the audit because the hooks may set other fields
+	findOptions := options.Find()
+	if !c.Options.AuditEnabled {
+		findOptions.SetProjection(bson.M{"_id": 1})
+	}
+	maxSize := c.Options.PaginationPolicy.allMaxSize()
+	if maxSize > 0 {
+		findOptions.SetLimit(int64(maxSize) + 1)
+	}
+	cursor, err := collection.Find(ctx.Request().Context(), query, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	docs := make([]bson.M, 0)
+	if err = cursor.All(ctx.Request().Context(), &docs); err != nil {
+		return nil, err
+	}
+	if maxSize > 0 && len(docs) > maxSize {
+		return nil, NewHttpErrorf(iris.StatusRequestEntityTooLarge, "the number of matched items exceeds %d", maxSize)
+	}
+	result := &UpdateManyResult{}
+	if len(docs) <= 0 {
+		return result, nil
+	}
+	ids := make([]interface{}, 0, len(docs))
+	sets := make([]bson.M, 0, len(docs))
+	for _, eachDoc := range docs {
+		eachId := eachDoc["_id"]
+		eachSet := copyBsonM(set)
+		if err = c.runBeforeUpdateHooks(ctx, eachId, eachSet); err != nil {
+			return nil, err
+		}
+		ids = append(ids, eachId)
+		sets = append(sets, eachSet)
+	}
+	// the changes are taken before the modification time is stamped
+	changes := make([][]AuditFieldChange, 0, len(docs))
+	if c.Options.AuditEnabled {
+		for i, eachDoc := range docs {
+			changes = append(changes, auditChanges(eachDoc, sets[i], nil))
+		}
+	}
+	for _, eachGroup := range groupUpdateManySets(ids, sets) {
+		c.hookUpdate(ctx, eachGroup.set)
+		update := bson.M{"$set": eachGroup.set}
+		if c.Options.ConcurrencyControlEnabled && len(c.Options.VersionField) > 0 {
+			update["$inc"] = bson.M{c.Options.VersionField: 1}
+		}
+		// the query is applied again in case the items are changed after they are found
+		filter := bson.M{"$and": bson.A{query, bson.M{"_id": bson.M{"$in": eachGroup.ids}}}}
+		updateResult, err := collection.UpdateMany(ctx.Request().Context(), filter, update)
+		if err != nil {
+			return nil, err
+		}
+		result.Matched += updateResult.MatchedCount
+		result.Modified += updateResult.ModifiedCount
+	}
+	if c.Options.AuditEnabled {
+		records := make([]*AuditRecord, 0, len(ids))
+		for i, eachId := range ids {
+			records = append(records, c.newAuditRecord(ctx, AuditActionUpdate, eachId, changes[i]))
+		}
+		c.writeAudit(ctx, records...)
+	}
+	for i, eachId := range ids {
+		// the items have been updated,so the error is only logged
+		if err = c.runAfterUpdateHooks(ctx, eachId, copyBsonM(sets[i])); err != nil {
+			ctx.Application().Logger().Errorf("after update hook of item %s failed,%s", c.idCodec().Format(eachId), err.Error())
+		}
+	}
+	return result, nil
+}
+
+type updateManyGroup struct {
+	set bson.M
+	ids []interface{}
+}
+
+// group the ids by their sets,in the order of the first id of each group
+func groupUpdateManySets(ids []interface{}, sets []bson.M) []*updateManyGroup {
+	groups := make([]*updateManyGroup, 0, 1)
+	for i, eachId := range ids {
+		var group *updateManyGroup
+		for _, eachGroup := range groups {
+			if reflect.DeepEqual(eachGroup.set, sets[i]) {
+				group = eachGroup
+				break
+			}
+		}
+		if group == nil {
+			group = &updateManyGroup{set: sets[i]}
+			groups = append(groups, group)
+		}
+		group.ids = append(group.ids, eachId)
+	}
+	return groups
+}
+
+func copyBsonM(m bson.M) bson.M {
+	copied := make(bson.M, len(m))
+	for key, value := range m {
+		copied[key] = value
+	}
+	return copied
+}
@@ -0,0 +1,28 @@
+package controllerx
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestGroupUpdateManySets(t *testing.T) {
+	ids := []interface{}{"1", "2", "3", "4"}
+	sets := []bson.M{
+		{"name": "a"},
+		{"name": "a", "note": "2"},
+		{"name": "a"},
+		{"name": "a", "note": "2"},
+	}
+	groups := groupUpdateManySets(ids, sets)
+	if len(groups) != 2 {
+		t.Fatalf("got %d groups,want 2", len(groups))
+	}
+	if !reflect.DeepEqual(groups[0].ids, []interface{}{"1", "3"}) || !reflect.DeepEqual(groups[0].set, bson.M{"name": "a"}) {
+		t.Fatalf("got first group %v %v", groups[0].ids, groups[0].set)
+	}
+	if !reflect.DeepEqual(groups[1].ids, []interface{}{"2", "4"}) || !reflect.DeepEqual(groups[1].set, bson.M{"name": "a", "note": "2"}) {
+		t.Fatalf("got second group %v %v", groups[1].ids, groups[1].set)
+	}
+}
@@ -0,0 +1,106 @@
+package controllerx
+
+import (
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+type updateTestEntity struct {
+	Id        string `json:"id" bson:"_id,omitempty"`
+	Name      string `json:"name" bson:"name"`
+	Note      string `json:"note,omitempty" bson:"note,omitempty"`
+	CreatorId string `json:"creatorId" bson:"creatorId"`
+	Secret    string `json:"-" bson:"secret"`
+	Version   int64  `json:"version" bson:"version,omitempty"`
+}
+
+func TestDiffBsonM(t *testing.T) {
+	entityFields := GetEntityFields(&updateTestEntity{})
+	protected := map[string]struct{}{"_id": {}, "id": {}, "creatorId": {}, "version": {}}
+	managed := map[string]struct{}{"creatorId": {}, "version": {}}
+	original := updateTestEntity{Id: "1", Name: "a", Note: "n", CreatorId: "u1", Secret: "s", Version: 3}
+	tests := []struct {
+		name      string
+		next      updateTestEntity
+		provided  map[string]interface{}
+		wantSet   bson.M
+		wantUnset bson.M
+	}{
+		{
+			name:      "no change",
+			next:      original,
+			wantSet:   bson.M{},
+			wantUnset: bson.M{},
+		},
+		{
+			name:      "changed field",
+			next:      updateTestEntity{Id: "1", Name: "b", Note: "n", CreatorId: "u1", Version: 3},
+			provided:  map[string]interface{}{"name": "b", "note": "n"},
+			wantSet:   bson.M{"name": "b"},
+			wantUnset: bson.M{},
+		},
+		{
+			name:      "omitted field is unset",
+			next:      updateTestEntity{Id: "1", Name: "a"},
+			provided:  map[string]interface{}{"name": "a"},
+			wantSet:   bson.M{},
+			wantUnset: bson.M{"note": ""},
+		},
+		{
+			name:      "managed fields keep their values",
+			next:      updateTestEntity{Id: "1", Name: "a", Note: "n", CreatorId: "u2", Version: 9},
+			provided:  map[string]interface{}{"name": "a", "note": "n", "creatorId": "u2", "version": 9},
+			wantSet:   bson.M{},
+			wantUnset: bson.M{},
+		},
+		{
+			name:      "patch keeps managed fields",
+			next:      updateTestEntity{Id: "1", Name: "c", Note: "n"},
+			wantSet:   bson.M{"name": "c"},
+			wantUnset: bson.M{},
+		},
+		{
+			name:      "provided protected field is changed",
+			next:      updateTestEntity{Id: "2", Name: "a", Note: "n"},
+			provided:  map[string]interface{}{"id": "2", "name": "a", "note": "n"},
+			wantSet:   bson.M{"_id": "2"},
+			wantUnset: bson.M{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			originalM, err := entityToBsonM(&original)
+			if err != nil {
+				t.Fatal(err)
+			}
+			nextM, err := entityToBsonM(&tt.next)
+			if err != nil {
+				t.Fatal(err)
+			}
+			set, unset := diffBsonM(entityFields, originalM, nextM, protected, managed, tt.provided)
+			if !reflect.DeepEqual(set, tt.wantSet) {
+				t.Fatalf("set got %v,want %v", set, tt.wantSet)
+			}
+			if !reflect.DeepEqual(unset, tt.wantUnset) {
+				t.Fatalf("unset got %v,want %v", unset, tt.wantUnset)
+			}
+		})
+	}
+}
+
+func TestDiffBsonMMissingVersion(t *testing.T) {
+	entityFields := GetEntityFields(&updateTestEntity{})
+	managed := map[string]struct{}{"version": {}}
+	// the document is created before the version field is added
+	originalM := bson.M{"_id": "1", "name": "a", "creatorId": "", "secret": ""}
+	nextM, err := entityToBsonM(&updateTestEntity{Id: "1", Name: "a", Version: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	set, unset := diffBsonM(entityFields, originalM, nextM, map[string]struct{}{}, managed, map[string]interface{}{"version": 5})
+	if len(set) > 0 || len(unset) > 0 {
+		t.Fatalf("got set %v,unset %v", set, unset)
+	}
+}
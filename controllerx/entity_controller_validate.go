@@ -0,0 +1,58 @@
+package controllerx
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/abmpio/mongodbr"
+	"github.com/go-playground/validator/v10"
+	"github.com/kataras/iris/v12"
+)
+
+// validate the fields of t,fields are json or bson names,
+// t is validated by mongodbr as a whole and only the errors of the fields are reported
+func (c *EntityController[T]) validatePartialEntity(t *T, fields []string) error {
+	entityFields := GetEntityFields(new(T))
+	structPaths := make([]string, 0, len(fields))
+	for _, eachField := range fields {
+		field := entityFields.Get(eachField)
+		if field == nil {
+			continue
+		}
+		structPaths = append(structPaths, field.StructPath)
+	}
+	if len(structPaths) <= 0 {
+		return nil
+	}
+	if err := filterValidationErrors(mongodbr.Validate(t), structPaths); err != nil {
+		return NewHttpError(iris.StatusBadRequest, err)
+	}
+	return nil
+}
+
+// keep the validation errors of the struct paths and their children,
+// the errors other than validator.ValidationErrors are returned as is
+func filterValidationErrors(err error, structPaths []string) error {
+	if err == nil {
+		return nil
+	}
+	var validationErrors validator.ValidationErrors
+	if !errors.As(err, &validationErrors) {
+		return err
+	}
+	filtered := make(validator.ValidationErrors, 0, len(validationErrors))
+	for _, eachError := range validationErrors {
+		// the namespace starts with the name of root struct
+		_, path, _ := strings.Cut(eachError.StructNamespace(), ".")
+		for _, eachPath := range structPaths {
+			if path == eachPath || strings.HasPrefix(path, eachPath+".") || strings.HasPrefix(path, eachPath+"[") {
+				filtered = append(filtered, eachError)
+				break
+			}
+		}
+	}
+	if len(filtered) <= 0 {
+		return nil
+	}
+	return filtered
+}
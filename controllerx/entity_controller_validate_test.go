@@ -0,0 +1,57 @@
+package controllerx
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type validateTestEntity struct {
+	Name    string `validate:"required"`
+	Email   string `validate:"required,email"`
+	Profile struct {
+		City string `validate:"required"`
+	}
+}
+
+func TestFilterValidationErrors(t *testing.T) {
+	err := validator.New().Struct(&validateTestEntity{Email: "x"})
+	if err == nil {
+		t.Fatal("expected validation errors")
+	}
+	tests := []struct {
+		name   string
+		paths  []string
+		fields []string
+	}{
+		{"no error of the fields", []string{"Other"}, nil},
+		{"error of a field", []string{"Email"}, []string{"Email"}},
+		{"error of a child", []string{"Profile"}, []string{"City"}},
+		{"errors of fields", []string{"Name", "Email"}, []string{"Name", "Email"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := filterValidationErrors(err, tt.paths)
+			if len(tt.fields) <= 0 {
+				if got != nil {
+					t.Fatalf("got %v,want nil", got)
+				}
+				return
+			}
+			var validationErrors validator.ValidationErrors
+			if !errors.As(got, &validationErrors) || len(validationErrors) != len(tt.fields) {
+				t.Fatalf("got %v,want errors of %v", got, tt.fields)
+			}
+			for i, eachError := range validationErrors {
+				if eachError.Field() != tt.fields[i] {
+					t.Fatalf("got field %s,want %s", eachError.Field(), tt.fields[i])
+				}
+			}
+		})
+	}
+	other := errors.New("other")
+	if got := filterValidationErrors(other, []string{"Name"}); got != other {
+		t.Fatalf("got %v,want %v", got, other)
+	}
+}
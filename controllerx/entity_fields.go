@@ -0,0 +1,154 @@
+package controllerx
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+)
+
+const (
+	// struct tag used to declare how a field can be changed by client,
+	// readonly: can not be set by create and update
+	// createonly: can be set by create but not update
+	EntityFieldTagName = "controllerx"
+
+	EntityFieldTagReadonly   = "readonly"
+	EntityFieldTagCreateonly = "createonly"
+)
+
+// EntityField describes a serialized field of entity struct
+type EntityField struct {
+	// struct field name
+	Name string
+	// struct field names from the root struct,separated by "."
+	StructPath string
+	JsonName   string
+	BsonName   string
+	Type       reflect.Type
+	Index      []int
+	Tag        reflect.StructTag
+}
+
+// names of field in payload,json name and bson name
+func (f *EntityField) Names() []string {
+	if f.JsonName == f.BsonName || len(f.JsonName) <= 0 {
+		return []string{f.BsonName}
+	}
+	if len(f.BsonName) <= 0 {
+		return []string{f.JsonName}
+	}
+	return []string{f.JsonName, f.BsonName}
+}
+
+// check the controllerx tag has the option
+func (f *EntityField) HasTagOption(option string) bool {
+	for _, eachOption := range strings.Split(f.Tag.Get(EntityFieldTagName), ",") {
+		if strings.TrimSpace(eachOption) == option {
+			return true
+		}
+	}
+	return false
+}
+
+// EntityFields is the serialized fields of entity struct
+type EntityFields struct {
+	List []*EntityField
+
+	byName map[string]*EntityField
+}
+
+// find field by json or bson name
+func (f *EntityFields) Get(name string) *EntityField {
+	return f.byName[name]
+}
+
+var _entityFieldsCache sync.Map
+
+// get the serialized fields of v's struct type,embedded structs are flattened
+func GetEntityFields(v interface{}) *EntityFields {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t == nil {
+		return &EntityFields{byName: map[string]*EntityField{}}
+	}
+	if cached, ok := _entityFieldsCache.Load(t); ok {
+		return cached.(*EntityFields)
+	}
+	fields := &EntityFields{
+		List:   make([]*EntityField, 0),
+		byName: make(map[string]*EntityField),
+	}
+	if t.Kind() == reflect.Struct {
+		collectEntityFields(t, nil, "", fields)
+	}
+	_entityFieldsCache.Store(t, fields)
+	return fields
+}
+
+func collectEntityFields(t reflect.Type, parentIndex []int, parentPath string, fields *EntityFields) {
+	for i := 0; i < t.NumField(); i++ {
+		structField := t.Field(i)
+		index := append(append([]int{}, parentIndex...), i)
+		structPath := structField.Name
+		if len(parentPath) > 0 {
+			structPath = parentPath + "." + structField.Name
+		}
+		jsonName, jsonSkip := parseFieldTagName(structField.Tag.Get("json"))
+		bsonName, bsonSkip := parseFieldTagName(structField.Tag.Get("bson"))
+		if jsonSkip && bsonSkip {
+			continue
+		}
+		if structField.Anonymous && len(jsonName) <= 0 {
+			fieldType := structField.Type
+			if fieldType.Kind() == reflect.Pointer {
+				fieldType = fieldType.Elem()
+			}
+			if fieldType.Kind() == reflect.Struct {
+				collectEntityFields(fieldType, index, structPath, fields)
+				continue
+			}
+		}
+		if !structField.IsExported() {
+			continue
+		}
+		if len(jsonName) <= 0 {
+			jsonName = structField.Name
+		}
+		if len(bsonName) <= 0 {
+			// default bson name is lowercased field name
+			bsonName = strings.ToLower(structField.Name)
+		}
+		if jsonSkip {
+			jsonName = ""
+		}
+		if bsonSkip {
+			bsonName = ""
+		}
+		field := &EntityField{
+			Name:       structField.Name,
+			StructPath: structPath,
+			JsonName:   jsonName,
+			BsonName:   bsonName,
+			Type:       structField.Type,
+			Index:      index,
+			Tag:        structField.Tag,
+		}
+		fields.List = append(fields.List, field)
+		for _, eachName := range field.Names() {
+			if _, ok := fields.byName[eachName]; !ok {
+				fields.byName[eachName] = field
+			}
+		}
+	}
+}
+
+// name of json or bson tag,skip is true when the tag is "-"
+func parseFieldTagName(tag string) (name string, skip bool) {
+	if tag == "-" {
+		return "", true
+	}
+	name, _, _ = strings.Cut(tag, ",")
+	return name, false
+}
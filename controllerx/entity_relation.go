@@ -0,0 +1,237 @@
+package controllerx
+
+import (
+	"strings"
+
+	"github.com/abmpio/entity"
+	"github.com/abmpio/mongodbr"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+const (
+	// query parameter or search input field of the relations to expand,e.g. ?expand=creator,category
+	ExpandParamName = "expand"
+)
+
+// IEntityRelation loads the entities referenced by a field of entity
+type IEntityRelation interface {
+	// name of relation used by expand parameter,also the json field of the expanded value
+	GetName() string
+	// json field which stores the referenced id or the list of ids
+	GetField() string
+	// load the referenced entities by ids for the request of ctx,the result is keyed by the formatted id
+	Load(ctx iris.Context, ids []string) (map[string]interface{}, error)
+}
+
+// EntityRelation is the relation to the entity U
+type EntityRelation[U mongodbr.IEntity] struct {
+	Name    string
+	Field   string
+	Service entity.IEntityService[U]
+	// parse the referenced ids,ObjectIdCodec is used if it is nil
+	IdCodec IIdCodec
+	// the fields of U never returned in the expanded value
+	HiddenFields []string
+	// the controller of U,its owner filter,soft delete filter and hidden fields are applied to the loaded entities,
+	// only the soft deleted entities are excluded if it is nil
+	Controller *EntityController[U]
+}
+
+// new relation named name,field references the entities of service
+func NewEntityRelation[U mongodbr.IEntity](name string, field string, service entity.IEntityService[U]) *EntityRelation[U] {
+	return &EntityRelation[U]{
+		Name:    name,
+		Field:   field,
+		Service: service,
+	}
+}
+
+// new relation named name,field references the entities of controller,
+// the entities are loaded as the read endpoints of controller do
+func NewEntityRelationOfController[U mongodbr.IEntity](name string, field string, controller *EntityController[U]) *EntityRelation[U] {
+	return &EntityRelation[U]{
+		Name:       name,
+		Field:      field,
+		Service:    controller.GetEntityService(),
+		IdCodec:    controller.Options.IdCodec,
+		Controller: controller,
+	}
+}
+
+func (r *EntityRelation[U]) GetName() string {
+	return r.Name
+}
+
+func (r *EntityRelation[U]) GetField() string {
+	return r.Field
+}
+
+func (r *EntityRelation[U]) idCodec() IIdCodec {
+	if r.IdCodec == nil {
+		return ObjectIdCodec{}
+	}
+	return r.IdCodec
+}
+
+// load the entities with one $in query,the invalid ids are ignored,
+// the entities which can not be read by the current user are not loaded
+func (r *EntityRelation[U]) Load(ctx iris.Context, ids []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	codec := r.idCodec()
+	values := make([]interface{}, 0, len(ids))
+	for _, eachId := range ids {
+		value, err := codec.Parse(eachId)
+		if err != nil {
+			continue
+		}
+		values = append(values, value)
+	}
+	if len(values) <= 0 {
+		return result, nil
+	}
+	filter := bson.M{"_id": bson.M{"$in": values}}
+	projection := ParseFieldProjection(nil)
+	var list []*U
+	var err error
+	if r.Controller != nil {
+		if err = r.Controller.applyOwnerFilter(ctx, filter); err != nil {
+			return nil, err
+		}
+		r.Controller.applySoftDeleteFilter(filter, false)
+		list, err = r.Controller.findList(ctx, filter, nil, 0, 0)
+		projection = r.Controller.fieldProjection(nil)
+	} else {
+		filter[SoftDeleteFieldIsDeleted] = bson.M{"$ne": true}
+		list, err = r.Service.FindList(filter)
+	}
+	if err != nil {
+		return nil, err
+	}
+	projection.Exclude = append(projection.Exclude, r.HiddenFields...)
+	for _, eachItem := range list {
+		m, err := entityToBsonM(eachItem)
+		if err != nil {
+			return nil, err
+		}
+		value, err := projection.Apply(eachItem)
+		if err != nil {
+			return nil, err
+		}
+		result[codec.Format(m["_id"])] = value
+	}
+	return result, nil
+}
+
+// expand relations of doc,doc is an item or a list of items decoded from json,
+// the expanded value is set to the relation name,null if the referenced entity is not found
+func ExpandRelations(ctx iris.Context, doc interface{}, relations []IEntityRelation) error {
+	items := make([]map[string]interface{}, 0)
+	switch v := doc.(type) {
+	case map[string]interface{}:
+		items = append(items, v)
+	case []interface{}:
+		for _, eachItem := range v {
+			if m, ok := eachItem.(map[string]interface{}); ok {
+				items = append(items, m)
+			}
+		}
+	}
+	if len(items) <= 0 {
+		return nil
+	}
+	for _, eachRelation := range relations {
+		ids := make([]string, 0)
+		uniqueIds := make(map[string]struct{})
+		for _, eachItem := range items {
+			for _, eachId := range referencedIds(eachItem[eachRelation.GetField()]) {
+				if _, ok := uniqueIds[eachId]; ok {
+					continue
+				}
+				uniqueIds[eachId] = struct{}{}
+				ids = append(ids, eachId)
+			}
+		}
+		loaded, err := eachRelation.Load(ctx, ids)
+		if err != nil {
+			return err
+		}
+		for _, eachItem := range items {
+			value := eachItem[eachRelation.GetField()]
+			if _, ok := value.([]interface{}); ok {
+				expanded := make([]interface{}, 0)
+				for _, eachId := range referencedIds(value) {
+					if related, ok := loaded[eachId]; ok {
+						expanded = append(expanded, related)
+					}
+				}
+				eachItem[eachRelation.GetName()] = expanded
+				continue
+			}
+			ids := referencedIds(value)
+			if len(ids) <= 0 {
+				eachItem[eachRelation.GetName()] = nil
+				continue
+			}
+			eachItem[eachRelation.GetName()] = loaded[ids[0]]
+		}
+	}
+	return nil
+}
+
+// ids of the json value,a string or a list of strings
+func referencedIds(value interface{}) []string {
+	switch v := value.(type) {
+	case string:
+		if len(v) > 0 {
+			return []string{v}
+		}
+	case []interface{}:
+		ids := make([]string, 0, len(v))
+		for _, eachValue := range v {
+			if s, ok := eachValue.(string); ok && len(s) > 0 {
+				ids = append(ids, s)
+			}
+		}
+		return ids
+	}
+	return nil
+}
+
+// find the relations to expand,unknown relation is rejected with 400
+func (c *EntityController[T]) expandRelations(expand []string) ([]IEntityRelation, error) {
+	relations := make([]IEntityRelation, 0)
+	added := make(map[string]struct{})
+	for _, eachExpand := range expand {
+		for _, eachName := range strings.Split(eachExpand, ",") {
+			eachName = strings.TrimSpace(eachName)
+			if len(eachName) <= 0 {
+				continue
+			}
+			if _, ok := added[eachName]; ok {
+				continue
+			}
+			relation := c.findRelation(eachName)
+			if relation == nil {
+				return nil, NewHttpErrorf(iris.StatusBadRequest, "unknown relation to expand:%s", eachName)
+			}
+			added[eachName] = struct{}{}
+			relations = append(relations, relation)
+		}
+	}
+	return relations, nil
+}
+
+func (c *EntityController[T]) findRelation(name string) IEntityRelation {
+	for _, eachRelation := range c.Options.Relations {
+		if eachRelation.GetName() == name {
+			return eachRelation
+		}
+	}
+	return nil
+}
+
+// relations requested by query string
+func getExpandParam(ctx iris.Context) []string {
+	return ctx.Request().URL.Query()[ExpandParamName]
+}
@@ -0,0 +1,44 @@
+package controllerx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kataras/iris/v12"
+)
+
+type fakeRelation struct {
+	loaded map[string]interface{}
+}
+
+func (r *fakeRelation) GetName() string  { return "creator" }
+func (r *fakeRelation) GetField() string { return "creatorId" }
+func (r *fakeRelation) Load(ctx iris.Context, ids []string) (map[string]interface{}, error) {
+	result := make(map[string]interface{})
+	for _, eachId := range ids {
+		if value, ok := r.loaded[eachId]; ok {
+			result[eachId] = value
+		}
+	}
+	return result, nil
+}
+
+func TestExpandRelations(t *testing.T) {
+	relation := &fakeRelation{loaded: map[string]interface{}{"u1": map[string]interface{}{"name": "a"}}}
+	doc := []interface{}{
+		map[string]interface{}{"id": "1", "creatorId": "u1"},
+		map[string]interface{}{"id": "2", "creatorId": "u2"},
+		map[string]interface{}{"id": "3"},
+	}
+	if err := ExpandRelations(nil, doc, []IEntityRelation{relation}); err != nil {
+		t.Fatal(err)
+	}
+	want := []interface{}{
+		map[string]interface{}{"id": "1", "creatorId": "u1", "creator": map[string]interface{}{"name": "a"}},
+		map[string]interface{}{"id": "2", "creatorId": "u2", "creator": nil},
+		map[string]interface{}{"id": "3", "creator": nil},
+	}
+	if !reflect.DeepEqual(doc, want) {
+		t.Fatalf("got %v,want %v", doc, want)
+	}
+}
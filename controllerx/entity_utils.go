@@ -0,0 +1,19 @@
+package controllerx
+
+import (
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+// convert entity to bson.M,keys are the bson field names
+func entityToBsonM(v interface{}) (bson.M, error) {
+	data, err := bson.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	m := bson.M{}
+	err = bson.Unmarshal(data, &m)
+	if err != nil {
+		return nil, err
+	}
+	return m, nil
+}
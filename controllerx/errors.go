@@ -0,0 +1,129 @@
+package controllerx
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/abmpio/irisx/casdoor"
+	"github.com/go-playground/validator/v10"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/mongo"
+)
+
+// FieldError is the field level detail of a validation failure
+type FieldError = casdoor.FieldError
+
+// HttpError is an error that carries the http status code which should be
+// returned to the client
+type HttpError struct {
+	StatusCode int
+	// machine-readable error code,the default code of StatusCode is used if it is empty
+	Code        string
+	Err         error
+	FieldErrors []FieldError
+}
+
+func NewHttpError(statusCode int, err error) *HttpError {
+	return &HttpError{
+		StatusCode: statusCode,
+		Err:        err,
+	}
+}
+
+func NewHttpErrorf(statusCode int, format string, args ...interface{}) *HttpError {
+	return NewHttpError(statusCode, fmt.Errorf(format, args...))
+}
+
+// new 400 error with field level details
+func NewValidationError(err error, fieldErrors ...FieldError) *HttpError {
+	return &HttpError{
+		StatusCode:  iris.StatusBadRequest,
+		Code:        casdoor.ErrorCodeValidationFailed,
+		Err:         err,
+		FieldErrors: fieldErrors,
+	}
+}
+
+// new 404 error for the missing entity
+func NewNotFoundError(id interface{}) *HttpError {
+	return &HttpError{
+		StatusCode: iris.StatusNotFound,
+		Code:       casdoor.ErrorCodeNotFound,
+		Err:        fmt.Errorf("not found item,id:%v", id),
+	}
+}
+
+// is err a 404 error,mongo.ErrNoDocuments is treated as not found
+func IsNotFoundError(err error) bool {
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return true
+	}
+	var httpErr *HttpError
+	return errors.As(err, &httpErr) && httpErr.StatusCode == iris.StatusNotFound
+}
+
+func (e *HttpError) Error() string {
+	if e.Err == nil {
+		return iris.StatusText(e.StatusCode)
+	}
+	return e.Err.Error()
+}
+
+func (e *HttpError) Unwrap() error {
+	return e.Err
+}
+
+// write err as application/problem+json response,
+// *HttpError use its own status code,mongo.ErrNoDocuments is treated as not found,
+// other errors are treated as internal server error
+func HandleError(ctx iris.Context, err error) {
+	if err == nil {
+		return
+	}
+	statusCode, code, fieldErrors := GetErrorDetails(err)
+	casdoor.WriteProblem(ctx, statusCode, code, err.Error(), fieldErrors...)
+}
+
+// get the status code,error code and field errors of err
+func GetErrorDetails(err error) (int, string, []FieldError) {
+	statusCode := iris.StatusInternalServerError
+	code := ""
+	fieldErrors := make([]FieldError, 0)
+	var httpErr *HttpError
+	if errors.As(err, &httpErr) {
+		statusCode = httpErr.StatusCode
+		code = httpErr.Code
+		fieldErrors = append(fieldErrors, httpErr.FieldErrors...)
+	} else if errors.Is(err, mongo.ErrNoDocuments) {
+		statusCode = iris.StatusNotFound
+		code = casdoor.ErrorCodeNotFound
+	}
+	var validationErrors validator.ValidationErrors
+	if errors.As(err, &validationErrors) {
+		if httpErr == nil {
+			statusCode = iris.StatusBadRequest
+		}
+		if len(code) <= 0 {
+			code = casdoor.ErrorCodeValidationFailed
+		}
+		for _, eachError := range validationErrors {
+			fieldErrors = append(fieldErrors, FieldError{
+				Field:   eachError.Field(),
+				Code:    eachError.Tag(),
+				Message: eachError.Error(),
+			})
+		}
+	}
+	if len(code) <= 0 {
+		code = casdoor.DefaultErrorCode(statusCode)
+	}
+	return statusCode, code, fieldErrors
+}
+
+func HandleErrorBadRequest(ctx iris.Context, err error) {
+	HandleError(ctx, NewHttpError(iris.StatusBadRequest, err))
+}
+
+func HandleErrorInternalServerError(ctx iris.Context, err error) {
+	HandleError(ctx, NewHttpError(iris.StatusInternalServerError, err))
+}
@@ -0,0 +1,296 @@
+package controllerx
+
+import (
+	"archive/zip"
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ExportFormat is the file format of export
+type ExportFormat string
+
+const (
+	ExportFormatCsv    ExportFormat = "csv"
+	ExportFormatNdjson ExportFormat = "ndjson"
+	ExportFormatXlsx   ExportFormat = "xlsx"
+)
+
+func (f ExportFormat) IsValid() bool {
+	return f == ExportFormatCsv || f == ExportFormatNdjson || f == ExportFormatXlsx
+}
+
+// content type of the format
+func (f ExportFormat) ContentType() string {
+	switch f {
+	case ExportFormatNdjson:
+		return "application/x-ndjson"
+	case ExportFormatXlsx:
+		return "application/vnd.openxmlformats-officedocument.spreadsheetml.sheet"
+	default:
+		return "text/csv; charset=utf-8"
+	}
+}
+
+// IExportWriter writes the exported rows,values are decoded from json
+type IExportWriter interface {
+	WriteRow(values []interface{}) error
+	// flush the buffered data and write the end of file
+	Close() error
+}
+
+// new writer of format,columns are written as header by csv and xlsx
+func NewExportWriter(format ExportFormat, w io.Writer, columns []ExportColumn) (IExportWriter, error) {
+	switch format {
+	case ExportFormatCsv:
+		return newCsvExportWriter(w, columns)
+	case ExportFormatNdjson:
+		return newNdjsonExportWriter(w, columns), nil
+	case ExportFormatXlsx:
+		return newXlsxExportWriter(w, columns)
+	default:
+		return nil, fmt.Errorf("unsupported export format:%s", format)
+	}
+}
+
+// format the json value as cell text,objects and arrays are written as json
+func formatExportValue(value interface{}) string {
+	switch v := value.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	case bool:
+		return strconv.FormatBool(v)
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	case json.Number:
+		return v.String()
+	default:
+		data, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Sprintf("%v", v)
+		}
+		return string(data)
+	}
+}
+
+// the text cells starting with these characters are evaluated as formulas by spreadsheet applications
+const exportFormulaPrefixes = "=+-@\t\r"
+
+// format the json value as text cell of csv,
+// the text which could be evaluated as formula is prefixed with ' so that it is shown as is,
+// the prefix is removed by unescapeImportFormula when the csv is imported
+func formatExportCell(value interface{}) string {
+	text := formatExportValue(value)
+	switch value.(type) {
+	case float64, json.Number:
+		return text
+	}
+	return escapeExportFormula(text)
+}
+
+func escapeExportFormula(text string) string {
+	if len(text) > 0 && strings.IndexByte(exportFormulaPrefixes, text[0]) >= 0 {
+		return "'" + text
+	}
+	return text
+}
+
+// remove the ' prefix added by escapeExportFormula
+func unescapeImportFormula(text string) string {
+	if len(text) > 1 && text[0] == '\'' && strings.IndexByte(exportFormulaPrefixes, text[1]) >= 0 {
+		return text[1:]
+	}
+	return text
+}
+
+type csvExportWriter struct {
+	writer *csv.Writer
+}
+
+func newCsvExportWriter(w io.Writer, columns []ExportColumn) (*csvExportWriter, error) {
+	writer := csv.NewWriter(w)
+	headers := make([]string, 0, len(columns))
+	for _, eachColumn := range columns {
+		headers = append(headers, escapeExportFormula(eachColumn.Header))
+	}
+	if err := writer.Write(headers); err != nil {
+		return nil, err
+	}
+	return &csvExportWriter{writer: writer}, nil
+}
+
+func (w *csvExportWriter) WriteRow(values []interface{}) error {
+	record := make([]string, 0, len(values))
+	for _, eachValue := range values {
+		record = append(record, formatExportCell(eachValue))
+	}
+	return w.writer.Write(record)
+}
+
+func (w *csvExportWriter) Close() error {
+	w.writer.Flush()
+	return w.writer.Error()
+}
+
+// ndjsonExportWriter writes each row as a json object keyed by the column fields
+type ndjsonExportWriter struct {
+	writer  *bufio.Writer
+	columns []ExportColumn
+}
+
+func newNdjsonExportWriter(w io.Writer, columns []ExportColumn) *ndjsonExportWriter {
+	return &ndjsonExportWriter{
+		writer:  bufio.NewWriter(w),
+		columns: columns,
+	}
+}
+
+func (w *ndjsonExportWriter) WriteRow(values []interface{}) error {
+	// keep the column order
+	w.writer.WriteByte('{')
+	for i, eachColumn := range w.columns {
+		if i > 0 {
+			w.writer.WriteByte(',')
+		}
+		key, err := json.Marshal(eachColumn.Field)
+		if err != nil {
+			return err
+		}
+		value, err := json.Marshal(values[i])
+		if err != nil {
+			return err
+		}
+		w.writer.Write(key)
+		w.writer.WriteByte(':')
+		w.writer.Write(value)
+	}
+	w.writer.WriteString("}\n")
+	if w.writer.Buffered() >= 32*1024 {
+		return w.writer.Flush()
+	}
+	return nil
+}
+
+func (w *ndjsonExportWriter) Close() error {
+	return w.writer.Flush()
+}
+
+const (
+	xlsxContentTypes = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Types xmlns="http://schemas.openxmlformats.org/package/2006/content-types">` +
+		`<Default Extension="rels" ContentType="application/vnd.openxmlformats-package.relationships+xml"/>` +
+		`<Default Extension="xml" ContentType="application/xml"/>` +
+		`<Override PartName="/xl/workbook.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.sheet.main+xml"/>` +
+		`<Override PartName="/xl/worksheets/sheet1.xml" ContentType="application/vnd.openxmlformats-officedocument.spreadsheetml.worksheet+xml"/>` +
+		`</Types>`
+	xlsxRootRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/officeDocument" Target="xl/workbook.xml"/>` +
+		`</Relationships>`
+	xlsxWorkbook = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<workbook xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main" xmlns:r="http://schemas.openxmlformats.org/officeDocument/2006/relationships">` +
+		`<sheets><sheet name="Sheet1" sheetId="1" r:id="rId1"/></sheets>` +
+		`</workbook>`
+	xlsxWorkbookRels = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<Relationships xmlns="http://schemas.openxmlformats.org/package/2006/relationships">` +
+		`<Relationship Id="rId1" Type="http://schemas.openxmlformats.org/officeDocument/2006/relationships/worksheet" Target="worksheets/sheet1.xml"/>` +
+		`</Relationships>`
+	xlsxSheetStart = `<?xml version="1.0" encoding="UTF-8" standalone="yes"?>
+<worksheet xmlns="http://schemas.openxmlformats.org/spreadsheetml/2006/main"><sheetData>`
+	xlsxSheetEnd = `</sheetData></worksheet>`
+)
+
+// xlsxExportWriter writes a workbook with one sheet,the rows are streamed to the sheet entry of zip
+type xlsxExportWriter struct {
+	zipWriter *zip.Writer
+	sheet     *bufio.Writer
+}
+
+func newXlsxExportWriter(w io.Writer, columns []ExportColumn) (*xlsxExportWriter, error) {
+	zipWriter := zip.NewWriter(w)
+	parts := []struct {
+		name    string
+		content string
+	}{
+		{"[Content_Types].xml", xlsxContentTypes},
+		{"_rels/.rels", xlsxRootRels},
+		{"xl/workbook.xml", xlsxWorkbook},
+		{"xl/_rels/workbook.xml.rels", xlsxWorkbookRels},
+	}
+	for _, eachPart := range parts {
+		partWriter, err := zipWriter.Create(eachPart.name)
+		if err != nil {
+			return nil, err
+		}
+		if _, err = io.WriteString(partWriter, eachPart.content); err != nil {
+			return nil, err
+		}
+	}
+	sheetWriter, err := zipWriter.Create("xl/worksheets/sheet1.xml")
+	if err != nil {
+		return nil, err
+	}
+	writer := &xlsxExportWriter{
+		zipWriter: zipWriter,
+		sheet:     bufio.NewWriter(sheetWriter),
+	}
+	writer.sheet.WriteString(xlsxSheetStart)
+	headers := make([]interface{}, 0, len(columns))
+	for _, eachColumn := range columns {
+		headers = append(headers, eachColumn.Header)
+	}
+	if err = writer.WriteRow(headers); err != nil {
+		return nil, err
+	}
+	return writer, nil
+}
+
+func (w *xlsxExportWriter) WriteRow(values []interface{}) error {
+	w.sheet.WriteString("<row>")
+	for _, eachValue := range values {
+		switch v := eachValue.(type) {
+		case nil:
+			w.sheet.WriteString("<c/>")
+		case float64:
+			w.sheet.WriteString(`<c t="n"><v>`)
+			w.sheet.WriteString(strconv.FormatFloat(v, 'f', -1, 64))
+			w.sheet.WriteString("</v></c>")
+		case json.Number:
+			w.sheet.WriteString(`<c t="n"><v>`)
+			w.sheet.WriteString(v.String())
+			w.sheet.WriteString("</v></c>")
+		case bool:
+			w.sheet.WriteString(`<c t="b"><v>`)
+			if v {
+				w.sheet.WriteString("1")
+			} else {
+				w.sheet.WriteString("0")
+			}
+			w.sheet.WriteString("</v></c>")
+		default:
+			w.sheet.WriteString(`<c t="inlineStr"><is><t xml:space="preserve">`)
+			// the inline strings are never evaluated as formulas,so they are not escaped
+			if err := xml.EscapeText(w.sheet, []byte(formatExportValue(v))); err != nil {
+				return err
+			}
+			w.sheet.WriteString("</t></is></c>")
+		}
+	}
+	_, err := w.sheet.WriteString("</row>")
+	return err
+}
+
+func (w *xlsxExportWriter) Close() error {
+	w.sheet.WriteString(xlsxSheetEnd)
+	if err := w.sheet.Flush(); err != nil {
+		return err
+	}
+	return w.zipWriter.Close()
+}
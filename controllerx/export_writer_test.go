@@ -0,0 +1,142 @@
+package controllerx
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io"
+	"strings"
+	"testing"
+)
+
+var exportTestColumns = []ExportColumn{
+	{Field: "name", Header: "Name"},
+	{Field: "age", Header: "Age"},
+	{Field: "profile", Header: "Profile"},
+}
+
+func writeExportRows(t *testing.T, format ExportFormat, rows [][]interface{}) []byte {
+	t.Helper()
+	buffer := &bytes.Buffer{}
+	writer, err := NewExportWriter(format, buffer, exportTestColumns)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, eachRow := range rows {
+		if err = writer.WriteRow(eachRow); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = writer.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buffer.Bytes()
+}
+
+func TestFormatExportCell(t *testing.T) {
+	tests := []struct {
+		name  string
+		value interface{}
+		want  string
+	}{
+		{"nil", nil, ""},
+		{"string", "a", "a"},
+		{"bool", true, "true"},
+		{"float", 1.5, "1.5"},
+		{"negative number", float64(-2), "-2"},
+		{"json number", json.Number("-9007199254740993"), "-9007199254740993"},
+		{"object", map[string]interface{}{"a": "b"}, `{"a":"b"}`},
+		{"formula", "=1+2", "'=1+2"},
+		{"plus", "+1", "'+1"},
+		{"minus", "-1", "'-1"},
+		{"at", "@SUM(A1)", "'@SUM(A1)"},
+		{"tab", "\t=1", "'\t=1"},
+		{"carriage return", "\r=1", "'\r=1"},
+		{"formula not at start", "a=1", "a=1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := formatExportCell(tt.value); got != tt.want {
+				t.Fatalf("got %q,want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCsvExportWriter(t *testing.T) {
+	got := string(writeExportRows(t, ExportFormatCsv, [][]interface{}{
+		{"a,b", json.Number("18"), map[string]interface{}{"city": "x"}},
+		{"=HYPERLINK(\"x\")", nil, nil},
+	}))
+	want := "Name,Age,Profile\n" +
+		"\"a,b\",18,\"{\"\"city\"\":\"\"x\"\"}\"\n" +
+		"\"'=HYPERLINK(\"\"x\"\")\",,\n"
+	if got != want {
+		t.Fatalf("got %q,want %q", got, want)
+	}
+}
+
+func TestNdjsonExportWriter(t *testing.T) {
+	got := string(writeExportRows(t, ExportFormatNdjson, [][]interface{}{
+		{"=a", json.Number("9007199254740993"), nil},
+		{"b", 1.5, map[string]interface{}{"city": "x"}},
+	}))
+	// ndjson is not opened by spreadsheet applications,so the values are kept as is
+	want := `{"name":"=a","age":9007199254740993,"profile":null}` + "\n" +
+		`{"name":"b","age":1.5,"profile":{"city":"x"}}` + "\n"
+	if got != want {
+		t.Fatalf("got %q,want %q", got, want)
+	}
+}
+
+func TestXlsxExportWriter(t *testing.T) {
+	data := writeExportRows(t, ExportFormatXlsx, [][]interface{}{
+		{"<a&b>", json.Number("18"), true},
+		{"@cmd", 1.5, nil},
+	})
+	reader, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, 0)
+	var sheet string
+	for _, eachFile := range reader.File {
+		names = append(names, eachFile.Name)
+		if eachFile.Name != "xl/worksheets/sheet1.xml" {
+			continue
+		}
+		f, err := eachFile.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := io.ReadAll(f)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		sheet = string(content)
+	}
+	if want := "[Content_Types].xml,_rels/.rels,xl/workbook.xml,xl/_rels/workbook.xml.rels,xl/worksheets/sheet1.xml"; strings.Join(names, ",") != want {
+		t.Fatalf("got parts %v", names)
+	}
+	for _, eachWant := range []string{
+		`<t xml:space="preserve">Name</t>`,
+		`<t xml:space="preserve">&lt;a&amp;b&gt;</t>`,
+		`<c t="n"><v>18</v></c>`,
+		`<c t="b"><v>1</v></c>`,
+		`<t xml:space="preserve">@cmd</t>`,
+		`<c t="n"><v>1.5</v></c>`,
+		`<c/>`,
+		`</sheetData></worksheet>`,
+	} {
+		if !strings.Contains(sheet, eachWant) {
+			t.Fatalf("sheet does not contain %s,sheet:%s", eachWant, sheet)
+		}
+	}
+}
+
+func TestNewExportWriterUnsupported(t *testing.T) {
+	if _, err := NewExportWriter(ExportFormat("pdf"), &bytes.Buffer{}, exportTestColumns); err == nil {
+		t.Fatal("expected error of unsupported format")
+	}
+}
@@ -0,0 +1,292 @@
+package controllerx
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+
+	"github.com/abmpio/mongodbr"
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// query parameter or search input field of the returned fields,
+	// e.g. ?fields=name,status to return only name and status,?fields=-secret to return all fields except secret
+	FieldsParamName = "fields"
+)
+
+// FieldProjection selects the json fields of the response items,
+// nested field is separated by "."
+type FieldProjection struct {
+	Include []string
+	Exclude []string
+}
+
+// parse fields,the field starts with "-" is excluded and the others are included
+func ParseFieldProjection(fields []string) *FieldProjection {
+	projection := &FieldProjection{
+		Include: make([]string, 0),
+		Exclude: make([]string, 0),
+	}
+	for _, eachField := range fields {
+		for _, eachName := range strings.Split(eachField, ",") {
+			eachName = strings.TrimSpace(eachName)
+			if strings.HasPrefix(eachName, "-") {
+				eachName = strings.TrimSpace(eachName[1:])
+				if len(eachName) > 0 {
+					projection.Exclude = append(projection.Exclude, eachName)
+				}
+				continue
+			}
+			if len(eachName) > 0 {
+				projection.Include = append(projection.Include, eachName)
+			}
+		}
+	}
+	return projection
+}
+
+func (p *FieldProjection) IsEmpty() bool {
+	return p == nil || (len(p.Include) <= 0 && len(p.Exclude) <= 0)
+}
+
+// apply projection to data,data is an item or a list of items,
+// they are converted to json objects before selecting fields
+func (p *FieldProjection) Apply(data interface{}) (interface{}, error) {
+	if p.IsEmpty() || data == nil {
+		return data, nil
+	}
+	doc, err := toJsonValue(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.applyJsonValue(doc), nil
+}
+
+// apply projection to the value decoded from json
+func (p *FieldProjection) applyJsonValue(doc interface{}) interface{} {
+	switch v := doc.(type) {
+	case []interface{}:
+		for i, eachItem := range v {
+			v[i] = p.applyItem(eachItem)
+		}
+		return v
+	default:
+		return p.applyItem(v)
+	}
+}
+
+func (p *FieldProjection) applyItem(item interface{}) interface{} {
+	object, ok := item.(map[string]interface{})
+	if !ok {
+		return item
+	}
+	if len(p.Include) > 0 {
+		included := make(map[string]interface{})
+		// id is always returned like mongo projection
+		for _, eachKey := range []string{"id", "_id"} {
+			if value, ok := object[eachKey]; ok {
+				included[eachKey] = value
+			}
+		}
+		for _, eachPath := range p.Include {
+			copyJsonPath(object, included, strings.Split(eachPath, "."))
+		}
+		object = included
+	}
+	for _, eachPath := range p.Exclude {
+		removeJsonPath(object, strings.Split(eachPath, "."))
+	}
+	return object
+}
+
+// copy the value at path from source to target
+func copyJsonPath(source map[string]interface{}, target map[string]interface{}, path []string) {
+	value, ok := source[path[0]]
+	if !ok {
+		return
+	}
+	if len(path) == 1 {
+		target[path[0]] = value
+		return
+	}
+	child, ok := value.(map[string]interface{})
+	if !ok {
+		return
+	}
+	targetChild, ok := target[path[0]].(map[string]interface{})
+	if !ok {
+		targetChild = make(map[string]interface{})
+		target[path[0]] = targetChild
+	}
+	copyJsonPath(child, targetChild, path[1:])
+}
+
+// remove the value at path,arrays of objects are traversed
+func removeJsonPath(node interface{}, path []string) {
+	switch v := node.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			delete(v, path[0])
+			return
+		}
+		if child, ok := v[path[0]]; ok {
+			removeJsonPath(child, path[1:])
+		}
+	case []interface{}:
+		for _, eachItem := range v {
+			removeJsonPath(eachItem, path)
+		}
+	}
+}
+
+// projection of the fields requested by client,the hidden fields are always excluded
+func (c *EntityController[T]) fieldProjection(fields []string) *FieldProjection {
+	projection := ParseFieldProjection(fields)
+	entityFields := GetEntityFields(new(T))
+	for _, eachField := range c.Options.HiddenFields {
+		name := eachField
+		if field := entityFields.Get(eachField); field != nil && len(field.JsonName) > 0 {
+			name = field.JsonName
+		}
+		projection.Exclude = append(projection.Exclude, name)
+	}
+	return projection
+}
+
+// mongo projection excluding the bson names of hidden fields,nil if there is no hidden field
+func (c *EntityController[T]) hiddenProjection() bson.M {
+	if len(c.Options.HiddenFields) <= 0 {
+		return nil
+	}
+	projection := bson.M{}
+	for _, eachField := range c.Options.HiddenFields {
+		projection[c.bsonFieldName(eachField)] = 0
+	}
+	return projection
+}
+
+// find the items for the read endpoints,page starts from 1 and all items are found if size is 0,
+// the hidden fields are excluded by the mongo projection so that they never leave the database
+func (c *EntityController[T]) findList(ctx iris.Context, filter bson.M, sorts []CursorSort, page int64, size int64) ([]*T, error) {
+	projection := c.hiddenProjection()
+	if projection == nil {
+		findOptions := fieldSortOptions(sorts)
+		if size > 0 {
+			findOptions = append(findOptions, mongodbr.MongodbrFindOptionWithPage(page, size))
+		}
+		return c.GetEntityService().FindList(filter, findOptions...)
+	}
+	collection, err := c.GetCollection()
+	if err != nil {
+		return nil, err
+	}
+	findOptions := options.Find().SetProjection(projection)
+	if len(sorts) > 0 {
+		findOptions.SetSort(mongoSort(sorts))
+	}
+	if size > 0 {
+		if page < 1 {
+			page = 1
+		}
+		findOptions.SetSkip((page - 1) * size).SetLimit(size)
+	}
+	cursor, err := collection.Find(ctx.Request().Context(), filter, findOptions)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]*T, 0)
+	if err = cursor.All(ctx.Request().Context(), &list); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// whether the field path is or is under a hidden field,the json and bson names are both checked
+func (c *EntityController[T]) isHiddenField(field string) bool {
+	if len(c.Options.HiddenFields) <= 0 {
+		return false
+	}
+	entityFields := GetEntityFields(new(T))
+	names := []string{field}
+	if name, _, ok := strings.Cut(field, "."); ok {
+		names = append(names, name)
+	}
+	for _, eachName := range names {
+		if entityField := entityFields.Get(eachName); entityField != nil {
+			names = append(names, entityField.Names()...)
+		}
+	}
+	for _, eachHidden := range c.Options.HiddenFields {
+		hiddenNames := []string{eachHidden}
+		if entityField := entityFields.Get(eachHidden); entityField != nil {
+			hiddenNames = append(hiddenNames, entityField.Names()...)
+		}
+		for _, eachHiddenName := range hiddenNames {
+			for _, eachName := range names {
+				if eachName == eachHiddenName || strings.HasPrefix(eachName, eachHiddenName+".") {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+// remove the hidden fields from the item returned by write endpoints
+func (c *EntityController[T]) hideFields(data interface{}) (interface{}, error) {
+	return c.fieldProjection(nil).Apply(data)
+}
+
+// run after find hooks,expand the relations and apply the field projection
+func (c *EntityController[T]) handleFindResult(ctx iris.Context, data interface{}, fields []string, expand []string) (interface{}, error) {
+	data, err := c.runAfterFindHooks(ctx, data)
+	if err != nil {
+		return nil, err
+	}
+	relations, err := c.expandRelations(expand)
+	if err != nil {
+		return nil, err
+	}
+	projection := c.fieldProjection(fields)
+	if len(relations) <= 0 && projection.IsEmpty() {
+		return data, nil
+	}
+	if len(projection.Include) > 0 {
+		// the expanded relations are returned even if they are not in the fields
+		for _, eachRelation := range relations {
+			projection.Include = append(projection.Include, eachRelation.GetName())
+		}
+	}
+	doc, err := toJsonValue(data)
+	if err != nil {
+		return nil, err
+	}
+	if err = ExpandRelations(ctx, doc, relations); err != nil {
+		return nil, err
+	}
+	return projection.applyJsonValue(doc), nil
+}
+
+// fields requested by query string
+func getFieldsParam(ctx iris.Context) []string {
+	return ctx.Request().URL.Query()[FieldsParamName]
+}
+
+// convert v to the value decoded from json,
+// numbers are decoded as json.Number so that int64 values keep their precision
+func toJsonValue(v interface{}) (interface{}, error) {
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+	var doc interface{}
+	if err = decoder.Decode(&doc); err != nil {
+		return nil, err
+	}
+	return doc, nil
+}
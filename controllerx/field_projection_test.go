@@ -0,0 +1,71 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestToJsonValue(t *testing.T) {
+	type item struct {
+		Id    int64   `json:"id"`
+		Score float64 `json:"score"`
+		Tags  []int64 `json:"tags"`
+	}
+	got, err := toJsonValue(&item{Id: 1<<53 + 1, Score: 1.5, Tags: []int64{9007199254740993}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"id":    json.Number("9007199254740993"),
+		"score": json.Number("1.5"),
+		"tags":  []interface{}{json.Number("9007199254740993")},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v,want %v", got, want)
+	}
+	data, err := json.Marshal(got)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"id":9007199254740993,"score":1.5,"tags":[9007199254740993]}` {
+		t.Fatalf("got %s", data)
+	}
+}
+
+func TestFieldProjectionApply(t *testing.T) {
+	doc := `{"id":"1","name":"a","secret":"s","profile":{"city":"x","phone":"p"},"items":[{"a":1,"b":2}]}`
+	tests := []struct {
+		name   string
+		fields []string
+		want   string
+	}{
+		{"no fields", nil, doc},
+		{"include", []string{"name"}, `{"id":"1","name":"a"}`},
+		{"include comma separated", []string{"name,profile.city"}, `{"id":"1","name":"a","profile":{"city":"x"}}`},
+		{"exclude", []string{"-secret,-profile.phone"}, `{"id":"1","name":"a","profile":{"city":"x"},"items":[{"a":1,"b":2}]}`},
+		{"exclude in array", []string{"-items.b"}, `{"id":"1","name":"a","secret":"s","profile":{"city":"x","phone":"p"},"items":[{"a":1}]}`},
+		{"include and exclude", []string{"name,profile", "-profile.phone"}, `{"id":"1","name":"a","profile":{"city":"x"}}`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var data interface{}
+			if err := json.Unmarshal([]byte(doc), &data); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseFieldProjection(tt.fields).Apply(data)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var want interface{}
+			if err = json.Unmarshal([]byte(tt.want), &want); err != nil {
+				t.Fatal(err)
+			}
+			gotData, _ := json.Marshal(got)
+			wantData, _ := json.Marshal(want)
+			if string(gotData) != string(wantData) {
+				t.Fatalf("got %s,want %s", gotData, wantData)
+			}
+		})
+	}
+}
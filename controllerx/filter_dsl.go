@@ -0,0 +1,342 @@
+package controllerx
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// FilterOperator is the operator of filter condition
+type FilterOperator string
+
+const (
+	FilterOperatorEq         FilterOperator = "eq"
+	FilterOperatorNe         FilterOperator = "ne"
+	FilterOperatorIn         FilterOperator = "in"
+	FilterOperatorNin        FilterOperator = "nin"
+	FilterOperatorGt         FilterOperator = "gt"
+	FilterOperatorGte        FilterOperator = "gte"
+	FilterOperatorLt         FilterOperator = "lt"
+	FilterOperatorLte        FilterOperator = "lte"
+	FilterOperatorContains   FilterOperator = "contains"
+	FilterOperatorStartsWith FilterOperator = "startsWith"
+	FilterOperatorExists     FilterOperator = "exists"
+	FilterOperatorBetween    FilterOperator = "between"
+
+	// error code of the rejected filter
+	ErrorCodeInvalidFilter = "invalid_filter"
+
+	// max nesting depth of and/or groups
+	MaxFilterDepth = 8
+)
+
+// all of the supported operators
+var FilterOperators = []FilterOperator{
+	FilterOperatorEq,
+	FilterOperatorNe,
+	FilterOperatorIn,
+	FilterOperatorNin,
+	FilterOperatorGt,
+	FilterOperatorGte,
+	FilterOperatorLt,
+	FilterOperatorLte,
+	FilterOperatorContains,
+	FilterOperatorStartsWith,
+	FilterOperatorExists,
+	FilterOperatorBetween,
+}
+
+// FilterExpression is a condition on a field or an and/or group of expressions,
+// e.g. {"or":[{"field":"name","op":"contains","value":"a"},{"field":"age","op":"between","value":[18,30]}]}
+type FilterExpression struct {
+	And []*FilterExpression `json:"and,omitempty"`
+	Or  []*FilterExpression `json:"or,omitempty"`
+
+	Field string         `json:"field,omitempty"`
+	Op    FilterOperator `json:"op,omitempty"`
+	Value interface{}    `json:"value,omitempty"`
+}
+
+// FilterCompiler compiles FilterExpression to bson filter
+type FilterCompiler struct {
+	// the filterable fields and their allowed operators,
+	// all of the operators are allowed if the list is empty,
+	// all of the fields are allowed if it is empty,otherwise the fields not in it are denied
+	Fields map[string][]FilterOperator
+	// the fields denied even if they are allowed by Fields,such as hidden fields,nothing is denied if it is nil
+	DenyFunc func(field string) bool
+	// map the field name of client to the document field name,the name is used as is if it is nil
+	FieldNameFunc func(field string) string
+	// convert the value of condition before compiling,the value is used as is if it is nil
+	ValueFunc func(field string, op FilterOperator, value interface{}) (interface{}, error)
+}
+
+// compile expression to bson filter,rejected expression returns *HttpError with status 400
+func (fc *FilterCompiler) Compile(expression *FilterExpression) (bson.M, error) {
+	if expression == nil {
+		return bson.M{}, nil
+	}
+	return fc.compile(expression, 0)
+}
+
+func (fc *FilterCompiler) compile(expression *FilterExpression, depth int) (bson.M, error) {
+	if depth > MaxFilterDepth {
+		return nil, newFilterError("", "filter is nested too deeply,max depth is %d", MaxFilterDepth)
+	}
+	isGroup := expression.And != nil || expression.Or != nil
+	if isGroup && (len(expression.Field) > 0 || len(expression.Op) > 0) {
+		return nil, newFilterError(expression.Field, "filter can not be both a condition and a group")
+	}
+	if expression.And != nil && expression.Or != nil {
+		return nil, newFilterError("", "filter can not have both and and or")
+	}
+	if expression.And != nil {
+		children, err := fc.compileChildren(expression.And, depth)
+		if err != nil {
+			return nil, err
+		}
+		return mergeFilters(children), nil
+	}
+	if expression.Or != nil {
+		children, err := fc.compileChildren(expression.Or, depth)
+		if err != nil {
+			return nil, err
+		}
+		if len(children) == 1 {
+			return children[0], nil
+		}
+		return bson.M{"$or": children}, nil
+	}
+	return fc.compileCondition(expression)
+}
+
+func (fc *FilterCompiler) compileChildren(expressions []*FilterExpression, depth int) ([]bson.M, error) {
+	// an empty group matches all or nothing by mistake,so it is rejected
+	if len(expressions) <= 0 {
+		return nil, newFilterError("", "and/or group of filter must not be empty")
+	}
+	children := make([]bson.M, 0, len(expressions))
+	for _, eachExpression := range expressions {
+		if eachExpression == nil {
+			return nil, newFilterError("", "expression of and/or group must not be null")
+		}
+		child, err := fc.compile(eachExpression, depth+1)
+		if err != nil {
+			return nil, err
+		}
+		children = append(children, child)
+	}
+	return children, nil
+}
+
+func (fc *FilterCompiler) compileCondition(expression *FilterExpression) (bson.M, error) {
+	field := expression.Field
+	if len(field) <= 0 {
+		return nil, newFilterError("", "field of filter must not be empty")
+	}
+	if !isSafeFilterField(field) {
+		return nil, newFilterError(field, "invalid filter field:%s", field)
+	}
+	op := expression.Op
+	if len(op) <= 0 {
+		op = FilterOperatorEq
+	}
+	if !isFilterOperatorSupported(op) {
+		return nil, newFilterError(field, "unsupported filter operator:%s", op)
+	}
+	if !fc.isAllowed(field, op) {
+		return nil, newFilterError(field, "filter %s on field %s is not allowed", op, field)
+	}
+	if err := checkFilterValue(expression.Value); err != nil {
+		return nil, newFilterError(field, "invalid value of field %s,%s", field, err.Error())
+	}
+	value, err := fc.convertValue(field, op, expression.Value)
+	if err != nil {
+		return nil, err
+	}
+	name := field
+	if fc.FieldNameFunc != nil {
+		name = fc.FieldNameFunc(field)
+	}
+
+	var condition interface{}
+	switch op {
+	case FilterOperatorEq, FilterOperatorNe, FilterOperatorGt, FilterOperatorGte, FilterOperatorLt, FilterOperatorLte:
+		if _, ok := toFilterList(value); ok && op != FilterOperatorEq && op != FilterOperatorNe {
+			return nil, newFilterError(field, "value of %s must not be an array", op)
+		}
+		condition = bson.M{"$" + string(op): value}
+	case FilterOperatorIn, FilterOperatorNin:
+		list, ok := toFilterList(value)
+		if !ok {
+			return nil, newFilterError(field, "value of %s must be an array", op)
+		}
+		condition = bson.M{"$" + string(op): list}
+	case FilterOperatorContains, FilterOperatorStartsWith:
+		s, ok := value.(string)
+		if !ok {
+			return nil, newFilterError(field, "value of %s must be a string", op)
+		}
+		pattern := regexp.QuoteMeta(s)
+		if op == FilterOperatorStartsWith {
+			pattern = "^" + pattern
+		}
+		condition = bson.M{"$regex": pattern, "$options": "i"}
+	case FilterOperatorExists:
+		exists, ok := value.(bool)
+		if !ok {
+			return nil, newFilterError(field, "value of exists must be a boolean")
+		}
+		condition = bson.M{"$exists": exists}
+	case FilterOperatorBetween:
+		list, ok := toFilterList(value)
+		if !ok || len(list) != 2 {
+			return nil, newFilterError(field, "value of between must be an array of 2 items")
+		}
+		condition = bson.M{"$gte": list[0], "$lte": list[1]}
+	}
+	return bson.M{name: condition}, nil
+}
+
+func (fc *FilterCompiler) isAllowed(field string, op FilterOperator) bool {
+	if fc.DenyFunc != nil && fc.DenyFunc(field) {
+		return false
+	}
+	if len(fc.Fields) <= 0 {
+		return true
+	}
+	operators, ok := fc.Fields[field]
+	if !ok {
+		return false
+	}
+	if len(operators) <= 0 {
+		return true
+	}
+	for _, eachOperator := range operators {
+		if eachOperator == op {
+			return true
+		}
+	}
+	return false
+}
+
+func (fc *FilterCompiler) convertValue(field string, op FilterOperator, value interface{}) (interface{}, error) {
+	if fc.ValueFunc == nil {
+		return value, nil
+	}
+	converted, err := fc.ValueFunc(field, op, value)
+	if err != nil {
+		return nil, newFilterError(field, "invalid value of field %s,%s", field, err.Error())
+	}
+	return converted, nil
+}
+
+// merge filters into one,$and is used when some of keys are duplicated
+func mergeFilters(filters []bson.M) bson.M {
+	merged := bson.M{}
+	for _, eachFilter := range filters {
+		for key := range eachFilter {
+			if _, ok := merged[key]; ok || strings.HasPrefix(key, "$") {
+				return bson.M{"$and": filters}
+			}
+			merged[key] = eachFilter[key]
+		}
+	}
+	return merged
+}
+
+func isFilterOperatorSupported(op FilterOperator) bool {
+	for _, eachOperator := range FilterOperators {
+		if eachOperator == op {
+			return true
+		}
+	}
+	return false
+}
+
+// field path must not contain mongo operator
+func isSafeFilterField(field string) bool {
+	if strings.ContainsAny(field, "$\x00") {
+		return false
+	}
+	for _, eachPart := range strings.Split(field, ".") {
+		if len(eachPart) <= 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// value must not contain the object which has mongo operator keys
+func checkFilterValue(value interface{}) error {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		return checkFilterValueMap(v)
+	case primitive.M:
+		return checkFilterValueMap(v)
+	case primitive.D:
+		for _, eachElement := range v {
+			if err := checkFilterValueMap(map[string]interface{}{eachElement.Key: eachElement.Value}); err != nil {
+				return err
+			}
+		}
+	case []interface{}:
+		for _, eachValue := range v {
+			if err := checkFilterValue(eachValue); err != nil {
+				return err
+			}
+		}
+	case primitive.A:
+		return checkFilterValue([]interface{}(v))
+	}
+	return nil
+}
+
+func checkFilterValueMap(m map[string]interface{}) error {
+	for key, eachValue := range m {
+		if strings.HasPrefix(key, "$") {
+			return fmt.Errorf("operator %s is not allowed in value", key)
+		}
+		if err := checkFilterValue(eachValue); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func toFilterList(value interface{}) ([]interface{}, bool) {
+	if list, ok := value.([]interface{}); ok {
+		return list, true
+	}
+	v := reflect.ValueOf(value)
+	if !v.IsValid() || (v.Kind() != reflect.Slice && v.Kind() != reflect.Array) || v.Type().Elem().Kind() == reflect.Uint8 {
+		return nil, false
+	}
+	list := make([]interface{}, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		list = append(list, v.Index(i).Interface())
+	}
+	return list, true
+}
+
+func newFilterError(field string, format string, args ...interface{}) *HttpError {
+	err := fmt.Errorf(format, args...)
+	httpErr := &HttpError{
+		StatusCode: iris.StatusBadRequest,
+		Code:       ErrorCodeInvalidFilter,
+		Err:        err,
+	}
+	if len(field) > 0 {
+		httpErr.FieldErrors = []FieldError{{
+			Field:   field,
+			Code:    ErrorCodeInvalidFilter,
+			Message: err.Error(),
+		}}
+	}
+	return httpErr
+}
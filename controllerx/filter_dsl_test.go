@@ -0,0 +1,143 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+)
+
+func TestFilterCompilerCompile(t *testing.T) {
+	compiler := &FilterCompiler{
+		Fields: map[string][]FilterOperator{
+			"name":    {},
+			"age":     {FilterOperatorGt, FilterOperatorBetween},
+			"tags":    {FilterOperatorIn},
+			"profile": {},
+		},
+	}
+	tests := []struct {
+		name       string
+		expression string
+		want       bson.M
+		wantErr    bool
+	}{
+		{"default eq", `{"field":"name","value":"a"}`, bson.M{"name": bson.M{"$eq": "a"}}, false},
+		{"allowed operator", `{"field":"age","op":"gt","value":18}`, bson.M{"age": bson.M{"$gt": float64(18)}}, false},
+		{"between", `{"field":"age","op":"between","value":[18,30]}`, bson.M{"age": bson.M{"$gte": float64(18), "$lte": float64(30)}}, false},
+		{"in", `{"field":"tags","op":"in","value":["a","b"]}`, bson.M{"tags": bson.M{"$in": []interface{}{"a", "b"}}}, false},
+		{"contains is escaped", `{"field":"name","op":"contains","value":"a.b"}`, bson.M{"name": bson.M{"$regex": `a\.b`, "$options": "i"}}, false},
+		{"startsWith", `{"field":"name","op":"startsWith","value":"a"}`, bson.M{"name": bson.M{"$regex": "^a", "$options": "i"}}, false},
+		{"exists", `{"field":"name","op":"exists","value":true}`, bson.M{"name": bson.M{"$exists": true}}, false},
+		{
+			"and merges fields",
+			`{"and":[{"field":"name","value":"a"},{"field":"age","op":"gt","value":1}]}`,
+			bson.M{"name": bson.M{"$eq": "a"}, "age": bson.M{"$gt": float64(1)}},
+			false,
+		},
+		{
+			"and with duplicated fields",
+			`{"and":[{"field":"name","value":"a"},{"field":"name","op":"ne","value":"b"}]}`,
+			bson.M{"$and": []bson.M{{"name": bson.M{"$eq": "a"}}, {"name": bson.M{"$ne": "b"}}}},
+			false,
+		},
+		{
+			"or",
+			`{"or":[{"field":"name","value":"a"},{"field":"name","value":"b"}]}`,
+			bson.M{"$or": []bson.M{{"name": bson.M{"$eq": "a"}}, {"name": bson.M{"$eq": "b"}}}},
+			false,
+		},
+		{"or of one", `{"or":[{"field":"name","value":"a"}]}`, bson.M{"name": bson.M{"$eq": "a"}}, false},
+		{"field not filterable", `{"field":"secret","value":"a"}`, nil, true},
+		{"operator not allowed", `{"field":"age","op":"lt","value":1}`, nil, true},
+		{"unsupported operator", `{"field":"name","op":"regex","value":"a"}`, nil, true},
+		{"operator in field", `{"field":"$where","value":"a"}`, nil, true},
+		{"empty field path part", `{"field":"profile..a","value":"a"}`, nil, true},
+		{"operator in value", `{"field":"profile","value":{"$ne":null}}`, nil, true},
+		{"nested operator in value", `{"field":"tags","op":"in","value":[{"a":{"$gt":1}}]}`, nil, true},
+		{"in requires array", `{"field":"tags","op":"in","value":"a"}`, nil, true},
+		{"between requires 2 items", `{"field":"age","op":"between","value":[1]}`, nil, true},
+		{"empty and", `{"and":[]}`, nil, true},
+		{"empty or", `{"or":[]}`, nil, true},
+		{"null child", `{"or":[null]}`, nil, true},
+		{"both and and or", `{"and":[{"field":"name","value":"a"}],"or":[{"field":"name","value":"b"}]}`, nil, true},
+		{"both condition and group", `{"field":"name","value":"a","and":[{"field":"name","value":"b"}]}`, nil, true},
+		{"empty condition", `{}`, nil, true},
+		{
+			"too deep",
+			`{"and":[{"and":[{"and":[{"and":[{"and":[{"and":[{"and":[{"and":[{"and":[{"field":"name","value":"a"}]}]}]}]}]}]}]}]}]}`,
+			nil,
+			true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expression := &FilterExpression{}
+			if err := json.Unmarshal([]byte(tt.expression), expression); err != nil {
+				t.Fatal(err)
+			}
+			got, err := compiler.Compile(expression)
+			if tt.wantErr {
+				var httpErr *HttpError
+				if !errors.As(err, &httpErr) || httpErr.StatusCode != 400 {
+					t.Fatalf("expected 400 error,got %v,%v", got, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFilterCompilerAllowAll(t *testing.T) {
+	compiler := &FilterCompiler{}
+	got, err := compiler.Compile(&FilterExpression{Field: "name", Value: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.M{"name": bson.M{"$eq": "a"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v,want %v", got, want)
+	}
+	got, err = compiler.Compile(nil)
+	if err != nil || len(got) > 0 {
+		t.Fatalf("got %v,%v", got, err)
+	}
+	compiler.DenyFunc = func(field string) bool { return field == "secret" }
+	if _, err = compiler.Compile(&FilterExpression{Field: "secret", Value: "a"}); err == nil {
+		t.Fatal("expected the denied field to be rejected")
+	}
+	compiler.Fields = map[string][]FilterOperator{"age": {}}
+	if _, err = compiler.Compile(&FilterExpression{Field: "name", Value: "a"}); err == nil {
+		t.Fatal("expected the field not in the whitelist to be rejected")
+	}
+}
+
+func TestFilterCompilerFuncs(t *testing.T) {
+	compiler := &FilterCompiler{
+		Fields:        map[string][]FilterOperator{"id": {}},
+		FieldNameFunc: func(field string) string { return "_" + field },
+		ValueFunc: func(field string, op FilterOperator, value interface{}) (interface{}, error) {
+			if value == "bad" {
+				return nil, errors.New("bad value")
+			}
+			return value.(string) + "!", nil
+		},
+	}
+	got, err := compiler.Compile(&FilterExpression{Field: "id", Value: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (bson.M{"_id": bson.M{"$eq": "a!"}}); !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v,want %v", got, want)
+	}
+	if _, err = compiler.Compile(&FilterExpression{Field: "id", Value: "bad"}); err == nil {
+		t.Fatal("expected the error of ValueFunc")
+	}
+}
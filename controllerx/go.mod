@@ -11,6 +11,9 @@ require (
 	github.com/abmpio/irisx/casdoor v0.0.0-20250316100020-50ae1cd9f370
 	github.com/abmpio/mongodbr v0.0.0-20250712084113-53e8110b7466
 	github.com/abmpio/webserver v0.0.0-20250316095628-f1dd590ed3be
+	github.com/casdoor/casdoor-go-sdk v1.5.0
+	github.com/go-playground/validator/v10 v10.19.0
+	github.com/google/uuid v1.6.0
 	github.com/kataras/iris/v12 v12.2.11
 	go.mongodb.org/mongo-driver v1.17.3
 )
@@ -27,7 +30,6 @@ require (
 	github.com/andybalholm/brotli v1.1.1 // indirect
 	github.com/armon/go-metrics v0.4.1 // indirect
 	github.com/aymerick/douceur v0.2.0 // indirect
-	github.com/casdoor/casdoor-go-sdk v1.5.0 // indirect
 	github.com/deckarep/golang-set v1.8.0 // indirect
 	github.com/fatih/color v1.18.0 // indirect
 	github.com/fatih/structs v1.1.0 // indirect
@@ -36,13 +38,11 @@ require (
 	github.com/gabriel-vasile/mimetype v1.4.3 // indirect
 	github.com/go-playground/locales v0.14.1 // indirect
 	github.com/go-playground/universal-translator v0.18.1 // indirect
-	github.com/go-playground/validator/v10 v10.19.0 // indirect
 	github.com/go-resty/resty/v2 v2.16.3 // indirect
 	github.com/go-viper/mapstructure/v2 v2.2.1 // indirect
 	github.com/golang-jwt/jwt/v4 v4.5.1 // indirect
 	github.com/golang/snappy v1.0.0 // indirect
 	github.com/gomarkdown/markdown v0.0.0-20250311123330-531bef5e742b // indirect
-	github.com/google/uuid v1.6.0 // indirect
 	github.com/gorilla/css v1.0.1 // indirect
 	github.com/hashicorp/consul/api v1.31.2 // indirect
 	github.com/hashicorp/errwrap v1.1.0 // indirect
@@ -116,4 +116,4 @@ require (
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
 
-replace github.com/abmpio/irisx/casdoor => ./../casdoor
+replace github.com/abmpio/irisx/casdoor => ../casdoor
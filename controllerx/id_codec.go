@@ -0,0 +1,98 @@
+package controllerx
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// IIdCodec converts between the id used in url and payload and the _id value stored in mongo
+type IIdCodec interface {
+	// parse the id from url or payload to the _id value
+	Parse(value string) (interface{}, error)
+	// format the _id value as string
+	Format(id interface{}) string
+}
+
+var (
+	_ IIdCodec = ObjectIdCodec{}
+	_ IIdCodec = StringIdCodec{}
+	_ IIdCodec = UuidIdCodec{}
+)
+
+// ObjectIdCodec is the default codec,_id is primitive.ObjectID
+type ObjectIdCodec struct{}
+
+func (ObjectIdCodec) Parse(value string) (interface{}, error) {
+	id, err := primitive.ObjectIDFromHex(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id,id must be bson id format,id:%s", value)
+	}
+	return id, nil
+}
+
+func (ObjectIdCodec) Format(id interface{}) string {
+	return formatId(id)
+}
+
+// StringIdCodec uses the id as is,_id is string
+type StringIdCodec struct{}
+
+func (StringIdCodec) Parse(value string) (interface{}, error) {
+	if len(strings.TrimSpace(value)) <= 0 {
+		return nil, fmt.Errorf("invalid id,id must not be blank")
+	}
+	return value, nil
+}
+
+func (StringIdCodec) Format(id interface{}) string {
+	return formatId(id)
+}
+
+// UuidIdCodec stores _id as the canonical uuid string
+type UuidIdCodec struct{}
+
+func (UuidIdCodec) Parse(value string) (interface{}, error) {
+	id, err := uuid.Parse(value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid id,id must be uuid format,id:%s", value)
+	}
+	return id.String(), nil
+}
+
+func (UuidIdCodec) Format(id interface{}) string {
+	return formatId(id)
+}
+
+func formatId(id interface{}) string {
+	switch v := id.(type) {
+	case primitive.ObjectID:
+		return v.Hex()
+	case *primitive.ObjectID:
+		if v == nil {
+			return ""
+		}
+		return v.Hex()
+	case string:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	default:
+		return fmt.Sprintf("%v", id)
+	}
+}
+
+// parse all ids with codec
+func parseIds(codec IIdCodec, values []string) ([]interface{}, error) {
+	ids := make([]interface{}, 0, len(values))
+	for _, eachValue := range values {
+		id, err := codec.Parse(eachValue)
+		if err != nil {
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
@@ -0,0 +1,284 @@
+package controllerx
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/kataras/iris/v12"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	// request header of idempotency key
+	IdempotencyKeyHeaderName = "Idempotency-Key"
+	// response header set when the stored response is replayed
+	IdempotentReplayedHeaderName = "Idempotent-Replayed"
+	// how long the response of a key is kept by default
+	DefaultIdempotencyTTL = 24 * time.Hour
+	// max length of idempotency key
+	MaxIdempotencyKeyLength = 255
+)
+
+// the endpoints supporting Idempotency-Key header
+func isIdempotencyEndpoint(endpoint EntityEndpoint) bool {
+	switch endpoint {
+	case EntityEndpointCreate, EntityEndpointBatch, EntityEndpointUpsert, EntityEndpointUpdateMany:
+		return true
+	}
+	return false
+}
+
+// IdempotencyRecord is the stored request and response of an idempotency key
+type IdempotencyRecord struct {
+	// the key scoped by user or client address,method and path
+	Key string `bson:"_id"`
+	// sha256 of request body
+	RequestHash string `bson:"requestHash"`
+	// false while the first request is being processed
+	Completed   bool      `bson:"completed"`
+	StatusCode  int       `bson:"statusCode"`
+	ContentType string    `bson:"contentType"`
+	Body        []byte    `bson:"body"`
+	ExpiresAt   time.Time `bson:"expiresAt"`
+}
+
+// IIdempotencyStore stores the records of idempotency keys
+type IIdempotencyStore interface {
+	// save record if there is no unexpired record of its key,
+	// return the existing record otherwise,nil means record is saved
+	Begin(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error)
+	// save the response of record
+	Complete(ctx context.Context, record *IdempotencyRecord) error
+	// remove the record,so that the request can be retried
+	Delete(ctx context.Context, key string) error
+}
+
+// IdempotencyMiddleware replays the stored response when a request is retried with the same Idempotency-Key,
+// the requests without the header are not affected
+type IdempotencyMiddleware struct {
+	Store IIdempotencyStore
+	TTL   time.Duration
+}
+
+func NewIdempotencyMiddleware(store IIdempotencyStore, ttl time.Duration) *IdempotencyMiddleware {
+	if ttl <= 0 {
+		ttl = DefaultIdempotencyTTL
+	}
+	return &IdempotencyMiddleware{
+		Store: store,
+		TTL:   ttl,
+	}
+}
+
+func (m *IdempotencyMiddleware) Serve(ctx iris.Context) {
+	key := ctx.GetHeader(IdempotencyKeyHeaderName)
+	if len(key) <= 0 {
+		ctx.Next()
+		return
+	}
+	if len(key) > MaxIdempotencyKeyLength {
+		HandleErrorBadRequest(ctx, errors.New("idempotency key is too long"))
+		return
+	}
+	// keep the body for the handler
+	ctx.RecordRequestBody(true)
+	body, err := ctx.GetBody()
+	if err != nil {
+		HandleErrorBadRequest(ctx, err)
+		return
+	}
+	hash := sha256.Sum256(body)
+	record := &IdempotencyRecord{
+		Key:         idempotencyScope(ctx) + ":" + ctx.Method() + ":" + ctx.Path() + ":" + key,
+		RequestHash: hex.EncodeToString(hash[:]),
+		ExpiresAt:   time.Now().Add(m.TTL),
+	}
+	requestContext := ctx.Request().Context()
+	existing, err := m.Store.Begin(requestContext, record)
+	if err != nil {
+		HandleErrorInternalServerError(ctx, err)
+		return
+	}
+	if existing != nil {
+		m.replay(ctx, record, existing)
+		return
+	}
+
+	completed := false
+	defer func() {
+		if completed {
+			return
+		}
+		// the record of the failed or panicked request is removed,so the request can be retried with the same key
+		recovered := recover()
+		if err := m.Store.Delete(context.WithoutCancel(requestContext), record.Key); err != nil {
+			ctx.Application().Logger().Errorf("delete idempotency record failed,%s", err.Error())
+		}
+		if recovered != nil {
+			panic(recovered)
+		}
+	}()
+	ctx.Record()
+	ctx.Next()
+	statusCode := ctx.GetStatusCode()
+	if statusCode >= iris.StatusInternalServerError {
+		return
+	}
+	completed = true
+	record.Completed = true
+	record.StatusCode = statusCode
+	record.ContentType = ctx.ResponseWriter().Header().Get("Content-Type")
+	record.Body = append([]byte(nil), ctx.Recorder().Body()...)
+	if err = m.Store.Complete(context.WithoutCancel(requestContext), record); err != nil {
+		ctx.Application().Logger().Errorf("save idempotency record failed,%s", err.Error())
+	}
+}
+
+// the scope of idempotency keys,it is the user id,
+// or the client address for anonymous requests so that the clients can not replay the responses of each other
+func idempotencyScope(ctx iris.Context) string {
+	if userId := GetUserId(ctx); len(userId) > 0 {
+		return userId
+	}
+	return "anonymous@" + ctx.RemoteAddr()
+}
+
+// write the stored response,the key reused with a different body or in progress is a conflict
+func (m *IdempotencyMiddleware) replay(ctx iris.Context, record *IdempotencyRecord, existing *IdempotencyRecord) {
+	if existing.RequestHash != record.RequestHash {
+		HandleError(ctx, NewHttpErrorf(iris.StatusConflict, "idempotency key has been used by a different request"))
+		return
+	}
+	if !existing.Completed {
+		HandleError(ctx, NewHttpErrorf(iris.StatusConflict, "request of the idempotency key is being processed"))
+		return
+	}
+	if len(existing.ContentType) > 0 {
+		ctx.ContentType(existing.ContentType)
+	}
+	ctx.Header(IdempotentReplayedHeaderName, "true")
+	ctx.StatusCode(existing.StatusCode)
+	ctx.Write(existing.Body)
+}
+
+// MemoryIdempotencyStore keeps the records in memory,the records are lost when the process exits
+// and not shared by the instances
+type MemoryIdempotencyStore struct {
+	mutex     sync.Mutex
+	records   map[string]*IdempotencyRecord
+	lastPurge time.Time
+}
+
+var _ IIdempotencyStore = (*MemoryIdempotencyStore)(nil)
+
+func NewMemoryIdempotencyStore() *MemoryIdempotencyStore {
+	return &MemoryIdempotencyStore{
+		records: make(map[string]*IdempotencyRecord),
+	}
+}
+
+func (s *MemoryIdempotencyStore) Begin(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	now := time.Now()
+	if now.Sub(s.lastPurge) >= time.Minute {
+		for key, eachRecord := range s.records {
+			if now.After(eachRecord.ExpiresAt) {
+				delete(s.records, key)
+			}
+		}
+		s.lastPurge = now
+	}
+	if existing, ok := s.records[record.Key]; ok && !now.After(existing.ExpiresAt) {
+		copied := *existing
+		return &copied, nil
+	}
+	copied := *record
+	s.records[record.Key] = &copied
+	return nil, nil
+}
+
+func (s *MemoryIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	copied := *record
+	s.records[record.Key] = &copied
+	return nil
+}
+
+func (s *MemoryIdempotencyStore) Delete(ctx context.Context, key string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	delete(s.records, key)
+	return nil
+}
+
+// MongoIdempotencyStore keeps the records in mongo collection,
+// call EnsureIndexes to remove the expired records by ttl index
+type MongoIdempotencyStore struct {
+	Collection *mongo.Collection
+}
+
+var _ IIdempotencyStore = (*MongoIdempotencyStore)(nil)
+
+func NewMongoIdempotencyStore(collection *mongo.Collection) *MongoIdempotencyStore {
+	return &MongoIdempotencyStore{
+		Collection: collection,
+	}
+}
+
+// create the ttl index of expiresAt
+func (s *MongoIdempotencyStore) EnsureIndexes(ctx context.Context) error {
+	_, err := s.Collection.Indexes().CreateOne(ctx, mongo.IndexModel{
+		Keys:    bson.D{{Key: "expiresAt", Value: 1}},
+		Options: options.Index().SetExpireAfterSeconds(0),
+	})
+	return err
+}
+
+func (s *MongoIdempotencyStore) Begin(ctx context.Context, record *IdempotencyRecord) (*IdempotencyRecord, error) {
+	_, err := s.Collection.InsertOne(ctx, record)
+	if err == nil {
+		return nil, nil
+	}
+	if !mongo.IsDuplicateKeyError(err) {
+		return nil, err
+	}
+	// take over the expired record which has not been removed by ttl index
+	result, err := s.Collection.ReplaceOne(ctx, bson.M{
+		"_id":       record.Key,
+		"expiresAt": bson.M{"$lt": time.Now()},
+	}, record)
+	if err != nil {
+		return nil, err
+	}
+	if result.MatchedCount > 0 {
+		return nil, nil
+	}
+	existing := &IdempotencyRecord{}
+	err = s.Collection.FindOne(ctx, bson.M{"_id": record.Key}).Decode(existing)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		// removed just now,try again
+		return s.Begin(ctx, record)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return existing, nil
+}
+
+func (s *MongoIdempotencyStore) Complete(ctx context.Context, record *IdempotencyRecord) error {
+	_, err := s.Collection.ReplaceOne(ctx, bson.M{"_id": record.Key}, record, options.Replace().SetUpsert(true))
+	return err
+}
+
+func (s *MongoIdempotencyStore) Delete(ctx context.Context, key string) error {
+	_, err := s.Collection.DeleteOne(ctx, bson.M{"_id": key})
+	return err
+}
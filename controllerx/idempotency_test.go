@@ -0,0 +1,134 @@
+package controllerx
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+func TestMemoryIdempotencyStore(t *testing.T) {
+	type step struct {
+		op string
+		// Begin returns the existing record with the hash,empty if nothing is returned
+		record       IdempotencyRecord
+		wantExisting string
+		wantComplete bool
+	}
+	future := time.Now().Add(time.Hour)
+	past := time.Now().Add(-time.Second)
+	tests := []struct {
+		name  string
+		steps []step
+	}{
+		{
+			"first request",
+			[]step{{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: future}}},
+		},
+		{
+			"in progress",
+			[]step{
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: future}},
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h2", ExpiresAt: future}, wantExisting: "h1"},
+			},
+		},
+		{
+			"completed",
+			[]step{
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: future}},
+				{op: "complete", record: IdempotencyRecord{Key: "a", RequestHash: "h1", Completed: true, StatusCode: 200, ExpiresAt: future}},
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: future}, wantExisting: "h1", wantComplete: true},
+			},
+		},
+		{
+			"deleted",
+			[]step{
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: future}},
+				{op: "delete", record: IdempotencyRecord{Key: "a"}},
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h2", ExpiresAt: future}},
+			},
+		},
+		{
+			"expired",
+			[]step{
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: past}},
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h2", ExpiresAt: future}},
+				{op: "begin", record: IdempotencyRecord{Key: "a", RequestHash: "h3", ExpiresAt: future}, wantExisting: "h2"},
+			},
+		},
+		{
+			"other key",
+			[]step{
+				{op: "begin", record: IdempotencyRecord{Key: "u1:POST:/items:a", RequestHash: "h1", ExpiresAt: future}},
+				{op: "begin", record: IdempotencyRecord{Key: "u2:POST:/items:a", RequestHash: "h1", ExpiresAt: future}},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := NewMemoryIdempotencyStore()
+			for i, eachStep := range tt.steps {
+				record := eachStep.record
+				switch eachStep.op {
+				case "begin":
+					existing, err := store.Begin(context.Background(), &record)
+					if err != nil {
+						t.Fatal(err)
+					}
+					got := ""
+					if existing != nil {
+						got = existing.RequestHash
+						if existing.Completed != eachStep.wantComplete {
+							t.Fatalf("step %d got completed %v,want %v", i, existing.Completed, eachStep.wantComplete)
+						}
+					}
+					if got != eachStep.wantExisting {
+						t.Fatalf("step %d got existing %q,want %q", i, got, eachStep.wantExisting)
+					}
+				case "complete":
+					if err := store.Complete(context.Background(), &record); err != nil {
+						t.Fatal(err)
+					}
+				case "delete":
+					if err := store.Delete(context.Background(), record.Key); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+		})
+	}
+}
+
+func TestMemoryIdempotencyStoreCopiesRecord(t *testing.T) {
+	store := NewMemoryIdempotencyStore()
+	record := &IdempotencyRecord{Key: "a", RequestHash: "h1", ExpiresAt: time.Now().Add(time.Hour)}
+	if _, err := store.Begin(context.Background(), record); err != nil {
+		t.Fatal(err)
+	}
+	record.Completed = true
+	existing, err := store.Begin(context.Background(), &IdempotencyRecord{Key: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if existing == nil || existing.Completed {
+		t.Fatalf("the stored record is changed by the caller,got %+v", existing)
+	}
+}
+
+func TestIsIdempotencyEndpoint(t *testing.T) {
+	tests := []struct {
+		endpoint EntityEndpoint
+		want     bool
+	}{
+		{EntityEndpointCreate, true},
+		{EntityEndpointBatch, true},
+		{EntityEndpointUpsert, true},
+		{EntityEndpointUpdateMany, true},
+		{EntityEndpointUpdate, false},
+		{EntityEndpointDelete, false},
+	}
+	for _, tt := range tests {
+		if got := isIdempotencyEndpoint(tt.endpoint); got != tt.want {
+			t.Fatalf("%s got %v,want %v", tt.endpoint, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,141 @@
+package controllerx
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/kataras/iris/v12"
+)
+
+// ImportFormat is the file format of import
+type ImportFormat string
+
+const (
+	ImportFormatJson   ImportFormat = "json"
+	ImportFormatNdjson ImportFormat = "ndjson"
+	ImportFormatCsv    ImportFormat = "csv"
+)
+
+// get import format from content type or file extension,empty if it is unknown
+func ParseImportFormat(value string) ImportFormat {
+	value = strings.ToLower(strings.TrimSpace(value))
+	if i := strings.LastIndexByte(value, '.'); i >= 0 && !strings.Contains(value, "/") {
+		value = value[i+1:]
+	}
+	value, _, _ = strings.Cut(value, ";")
+	switch strings.TrimSpace(value) {
+	case "json", "application/json":
+		return ImportFormatJson
+	case "ndjson", "jsonl", "application/x-ndjson", "application/ndjson", "application/jsonl":
+		return ImportFormatNdjson
+	case "csv", "text/csv", "application/csv":
+		return ImportFormatCsv
+	}
+	return ""
+}
+
+// ImportRow is a row of imported file
+type ImportRow struct {
+	// row number from 1,it is the line number for ndjson,the header of csv is not counted
+	Row  int
+	Data json.RawMessage
+	// error when reading the row
+	Err error
+}
+
+// read rows of the json array,ndjson or csv file,
+// csv values are converted by convertCsvRow to a json object
+func ReadImportRows(format ImportFormat, r io.Reader, maxRows int, convertCsvRow func(headers []string, record []string) (map[string]interface{}, error)) ([]ImportRow, error) {
+	rows := make([]ImportRow, 0)
+	appendRow := func(row ImportRow) error {
+		if maxRows > 0 && len(rows) >= maxRows {
+			return NewHttpErrorf(iris.StatusRequestEntityTooLarge, "the number of rows exceeds %d", maxRows)
+		}
+		rows = append(rows, row)
+		return nil
+	}
+	switch format {
+	case ImportFormatJson:
+		decoder := json.NewDecoder(r)
+		token, err := decoder.Token()
+		if err != nil {
+			return nil, err
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			return nil, fmt.Errorf("json payload must be an array")
+		}
+		for decoder.More() {
+			var data json.RawMessage
+			if err = decoder.Decode(&data); err != nil {
+				return nil, err
+			}
+			if err = appendRow(ImportRow{Row: len(rows) + 1, Data: data}); err != nil {
+				return nil, err
+			}
+		}
+	case ImportFormatNdjson:
+		reader := bufio.NewReader(r)
+		lineNumber := 0
+		for {
+			line, err := reader.ReadBytes('\n')
+			lineNumber++
+			if len(bytes.TrimSpace(line)) > 0 {
+				if err := appendRow(ImportRow{Row: lineNumber, Data: json.RawMessage(bytes.TrimSpace(line))}); err != nil {
+					return nil, err
+				}
+			}
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+		}
+	case ImportFormatCsv:
+		reader := csv.NewReader(r)
+		reader.FieldsPerRecord = -1
+		headers, err := reader.Read()
+		if err != nil {
+			return nil, err
+		}
+		if len(headers) > 0 {
+			// remove utf-8 bom written by excel
+			headers[0] = strings.TrimPrefix(headers[0], "\ufeff")
+		}
+		unescapeImportRecord(headers)
+		for {
+			record, err := reader.Read()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			unescapeImportRecord(record)
+			row := ImportRow{Row: len(rows) + 1}
+			m, err := convertCsvRow(headers, record)
+			if err == nil {
+				row.Data, err = json.Marshal(m)
+			}
+			row.Err = err
+			if err = appendRow(row); err != nil {
+				return nil, err
+			}
+		}
+	default:
+		return nil, fmt.Errorf("unsupported import format:%s", format)
+	}
+	return rows, nil
+}
+
+// the values escaped by the csv export are restored,so the exported file can be imported as is
+func unescapeImportRecord(record []string) {
+	for i, eachValue := range record {
+		record[i] = unescapeImportFormula(eachValue)
+	}
+}
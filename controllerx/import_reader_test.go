@@ -0,0 +1,106 @@
+package controllerx
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseImportFormat(t *testing.T) {
+	tests := []struct {
+		value string
+		want  ImportFormat
+	}{
+		{"json", ImportFormatJson},
+		{"application/json; charset=utf-8", ImportFormatJson},
+		{"items.JSON", ImportFormatJson},
+		{"jsonl", ImportFormatNdjson},
+		{"application/x-ndjson", ImportFormatNdjson},
+		{"data.ndjson", ImportFormatNdjson},
+		{"text/csv", ImportFormatCsv},
+		{"export.2024.csv", ImportFormatCsv},
+		{"application/xml", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := ParseImportFormat(tt.value); got != tt.want {
+			t.Fatalf("%s got %s,want %s", tt.value, got, tt.want)
+		}
+	}
+}
+
+// convert csv record to object keyed by headers,the value "bad" is rejected
+func testConvertCsvRow(headers []string, record []string) (map[string]interface{}, error) {
+	m := make(map[string]interface{})
+	for i, eachValue := range record {
+		if eachValue == "bad" {
+			return nil, fmt.Errorf("bad value")
+		}
+		if i < len(headers) && len(eachValue) > 0 {
+			m[headers[i]] = eachValue
+		}
+	}
+	return m, nil
+}
+
+func TestReadImportRows(t *testing.T) {
+	type wantRow struct {
+		row    int
+		data   string
+		hasErr bool
+	}
+	tests := []struct {
+		name    string
+		format  ImportFormat
+		body    string
+		maxRows int
+		want    []wantRow
+		wantErr bool
+	}{
+		{"json array", ImportFormatJson, `[{"a":1}, {"a":2}]`, 0, []wantRow{{1, `{"a":1}`, false}, {2, `{"a":2}`, false}}, false},
+		{"empty json array", ImportFormatJson, `[]`, 0, []wantRow{}, false},
+		{"json object", ImportFormatJson, `{"a":1}`, 0, nil, true},
+		{"invalid json", ImportFormatJson, `[{"a":`, 0, nil, true},
+		{"json exceeds max rows", ImportFormatJson, `[{},{},{}]`, 2, nil, true},
+		{"ndjson", ImportFormatNdjson, "{\"a\":1}\n\n {\"a\":2} \n", 0, []wantRow{{1, `{"a":1}`, false}, {3, `{"a":2}`, false}}, false},
+		{"ndjson without last newline", ImportFormatNdjson, "{\"a\":1}\n{\"a\":2}", 0, []wantRow{{1, `{"a":1}`, false}, {2, `{"a":2}`, false}}, false},
+		{"ndjson exceeds max rows", ImportFormatNdjson, "{}\n{}\n", 1, nil, true},
+		{"csv", ImportFormatCsv, "\ufeffa,b\n1,x\n,y\n", 0, []wantRow{{1, `{"a":"1","b":"x"}`, false}, {2, `{"b":"y"}`, false}}, false},
+		{"csv escaped formula", ImportFormatCsv, "'=a,b\n'@x,'y\n", 0, []wantRow{{1, `{"=a":"@x","b":"'y"}`, false}}, false},
+		{"csv row error", ImportFormatCsv, "a\nbad\n2\n", 0, []wantRow{{1, ``, true}, {2, `{"a":"2"}`, false}}, false},
+		{"csv without header", ImportFormatCsv, "", 0, nil, true},
+		{"csv exceeds max rows", ImportFormatCsv, "a\n1\n2\n", 1, nil, true},
+		{"unsupported format", ImportFormat("xml"), "<a/>", 0, nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rows, err := ReadImportRows(tt.format, strings.NewReader(tt.body), tt.maxRows, testConvertCsvRow)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %v", rows)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			got := make([]wantRow, 0, len(rows))
+			for _, eachRow := range rows {
+				got = append(got, wantRow{eachRow.Row, string(eachRow.Data), eachRow.Err != nil})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %v,want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadImportRowsMaxRowsStatus(t *testing.T) {
+	_, err := ReadImportRows(ImportFormatJson, strings.NewReader(`[{},{}]`), 1, nil)
+	var httpErr *HttpError
+	if !errors.As(err, &httpErr) || httpErr.StatusCode != 413 {
+		t.Fatalf("expected 413 error,got %v", err)
+	}
+}
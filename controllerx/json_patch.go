@@ -0,0 +1,268 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+const (
+	ContentTypeMergePatch = "application/merge-patch+json"
+	ContentTypeJsonPatch  = "application/json-patch+json"
+)
+
+// apply json merge patch(RFC 7396) to target,
+// target and patch are the values decoded from json
+func ApplyMergePatch(target interface{}, patch interface{}) interface{} {
+	patchObject, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	targetObject, ok := target.(map[string]interface{})
+	if !ok {
+		targetObject = make(map[string]interface{})
+	}
+	for key, value := range patchObject {
+		if value == nil {
+			delete(targetObject, key)
+			continue
+		}
+		targetObject[key] = ApplyMergePatch(targetObject[key], value)
+	}
+	return targetObject
+}
+
+// JsonPatchOperation is an operation of json patch(RFC 6902)
+type JsonPatchOperation struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from,omitempty"`
+	Value json.RawMessage `json:"value,omitempty"`
+}
+
+// apply json patch(RFC 6902) operations to doc,doc is the value decoded from json
+func ApplyJsonPatch(doc interface{}, operations []JsonPatchOperation) (interface{}, error) {
+	var err error
+	for _, eachOperation := range operations {
+		doc, err = applyJsonPatchOperation(doc, eachOperation)
+		if err != nil {
+			return nil, fmt.Errorf("json patch operation %s %s failed,%s", eachOperation.Op, eachOperation.Path, err.Error())
+		}
+	}
+	return doc, nil
+}
+
+func applyJsonPatchOperation(doc interface{}, operation JsonPatchOperation) (interface{}, error) {
+	path, err := parseJsonPointer(operation.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch operation.Op {
+	case "add", "replace", "test":
+		if len(operation.Value) <= 0 {
+			return nil, fmt.Errorf("value is required")
+		}
+		var value interface{}
+		if err = json.Unmarshal(operation.Value, &value); err != nil {
+			return nil, err
+		}
+		if operation.Op == "test" {
+			current, err := jsonPointerGet(doc, path)
+			if err != nil {
+				return nil, err
+			}
+			if !reflect.DeepEqual(current, value) {
+				return nil, fmt.Errorf("test failed")
+			}
+			return doc, nil
+		}
+		return jsonPointerSet(doc, path, value, operation.Op == "replace")
+	case "remove":
+		doc, _, err = jsonPointerRemove(doc, path)
+		return doc, err
+	case "move", "copy":
+		from, err := parseJsonPointer(operation.From)
+		if err != nil {
+			return nil, err
+		}
+		var value interface{}
+		if operation.Op == "move" {
+			doc, value, err = jsonPointerRemove(doc, from)
+		} else {
+			value, err = jsonPointerGet(doc, from)
+			if err == nil {
+				value, err = deepCopyJsonValue(value)
+			}
+		}
+		if err != nil {
+			return nil, err
+		}
+		return jsonPointerSet(doc, path, value, false)
+	default:
+		return nil, fmt.Errorf("unsupported op")
+	}
+}
+
+// parse json pointer(RFC 6901) to reference tokens
+func parseJsonPointer(pointer string) ([]string, error) {
+	if len(pointer) <= 0 {
+		return []string{}, nil
+	}
+	if !strings.HasPrefix(pointer, "/") {
+		return nil, fmt.Errorf("invalid json pointer:%s", pointer)
+	}
+	tokens := strings.Split(pointer[1:], "/")
+	for i, eachToken := range tokens {
+		eachToken = strings.ReplaceAll(eachToken, "~1", "/")
+		tokens[i] = strings.ReplaceAll(eachToken, "~0", "~")
+	}
+	return tokens, nil
+}
+
+func parseJsonArrayIndex(token string, max int) (int, error) {
+	index, err := strconv.Atoi(token)
+	if err != nil || index < 0 || index > max || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index:%s", token)
+	}
+	return index, nil
+}
+
+func jsonPointerGet(doc interface{}, path []string) (interface{}, error) {
+	current := doc
+	for _, eachToken := range path {
+		switch node := current.(type) {
+		case map[string]interface{}:
+			value, ok := node[eachToken]
+			if !ok {
+				return nil, fmt.Errorf("path not found:%s", eachToken)
+			}
+			current = value
+		case []interface{}:
+			index, err := parseJsonArrayIndex(eachToken, len(node)-1)
+			if err != nil {
+				return nil, err
+			}
+			current = node[index]
+		default:
+			return nil, fmt.Errorf("path not found:%s", eachToken)
+		}
+	}
+	return current, nil
+}
+
+// set value at path and return the new doc,
+// replace requires the value exists,otherwise the value is added or inserted into array
+func jsonPointerSet(doc interface{}, path []string, value interface{}, replace bool) (interface{}, error) {
+	if len(path) <= 0 {
+		return value, nil
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		if len(path) == 1 {
+			if _, ok := node[token]; replace && !ok {
+				return nil, fmt.Errorf("path not found:%s", token)
+			}
+			node[token] = value
+			return node, nil
+		}
+		child, ok := node[token]
+		if !ok {
+			return nil, fmt.Errorf("path not found:%s", token)
+		}
+		newChild, err := jsonPointerSet(child, path[1:], value, replace)
+		if err != nil {
+			return nil, err
+		}
+		node[token] = newChild
+		return node, nil
+	case []interface{}:
+		if len(path) == 1 {
+			if token == "-" && !replace {
+				return append(node, value), nil
+			}
+			max := len(node)
+			if replace {
+				max = len(node) - 1
+			}
+			index, err := parseJsonArrayIndex(token, max)
+			if err != nil {
+				return nil, err
+			}
+			if replace {
+				node[index] = value
+				return node, nil
+			}
+			node = append(node, nil)
+			copy(node[index+1:], node[index:])
+			node[index] = value
+			return node, nil
+		}
+		index, err := parseJsonArrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, err
+		}
+		newChild, err := jsonPointerSet(node[index], path[1:], value, replace)
+		if err != nil {
+			return nil, err
+		}
+		node[index] = newChild
+		return node, nil
+	default:
+		return nil, fmt.Errorf("path not found:%s", token)
+	}
+}
+
+// remove the value at path,return the new doc and the removed value
+func jsonPointerRemove(doc interface{}, path []string) (interface{}, interface{}, error) {
+	if len(path) <= 0 {
+		return nil, nil, fmt.Errorf("can not remove the root")
+	}
+	token := path[0]
+	switch node := doc.(type) {
+	case map[string]interface{}:
+		child, ok := node[token]
+		if !ok {
+			return nil, nil, fmt.Errorf("path not found:%s", token)
+		}
+		if len(path) == 1 {
+			delete(node, token)
+			return node, child, nil
+		}
+		newChild, removed, err := jsonPointerRemove(child, path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		node[token] = newChild
+		return node, removed, nil
+	case []interface{}:
+		index, err := parseJsonArrayIndex(token, len(node)-1)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(path) == 1 {
+			removed := node[index]
+			return append(node[:index], node[index+1:]...), removed, nil
+		}
+		newChild, removed, err := jsonPointerRemove(node[index], path[1:])
+		if err != nil {
+			return nil, nil, err
+		}
+		node[index] = newChild
+		return node, removed, nil
+	default:
+		return nil, nil, fmt.Errorf("path not found:%s", token)
+	}
+}
+
+func deepCopyJsonValue(v interface{}) (interface{}, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var copied interface{}
+	err = json.Unmarshal(data, &copied)
+	return copied, err
+}
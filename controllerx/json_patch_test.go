@@ -0,0 +1,89 @@
+package controllerx
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func decodeJsonValue(t *testing.T, data string) interface{} {
+	t.Helper()
+	var v interface{}
+	if err := json.Unmarshal([]byte(data), &v); err != nil {
+		t.Fatal(err)
+	}
+	return v
+}
+
+func TestApplyMergePatch(t *testing.T) {
+	tests := []struct {
+		name   string
+		target string
+		patch  string
+		want   string
+	}{
+		{"replace value", `{"a":"b"}`, `{"a":"c"}`, `{"a":"c"}`},
+		{"add value", `{"a":"b"}`, `{"b":"c"}`, `{"a":"b","b":"c"}`},
+		{"remove by null", `{"a":"b","b":"c"}`, `{"a":null}`, `{"b":"c"}`},
+		{"replace array", `{"a":["b"]}`, `{"a":["c","d"]}`, `{"a":["c","d"]}`},
+		{"nested object", `{"a":{"b":"c","d":"e"}}`, `{"a":{"b":"f","d":null}}`, `{"a":{"b":"f"}}`},
+		{"object replaces scalar", `{"a":"b"}`, `{"a":{"c":"d"}}`, `{"a":{"c":"d"}}`},
+		{"non object patch", `{"a":"b"}`, `["c"]`, `["c"]`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := ApplyMergePatch(decodeJsonValue(t, tt.target), decodeJsonValue(t, tt.patch))
+			if want := decodeJsonValue(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v,want %v", got, want)
+			}
+		})
+	}
+}
+
+func TestApplyJsonPatch(t *testing.T) {
+	tests := []struct {
+		name       string
+		doc        string
+		operations string
+		want       string
+		wantErr    bool
+	}{
+		{"add field", `{"a":1}`, `[{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, false},
+		{"add to array end", `{"a":[1]}`, `[{"op":"add","path":"/a/-","value":2}]`, `{"a":[1,2]}`, false},
+		{"insert into array", `{"a":[1,3]}`, `[{"op":"add","path":"/a/1","value":2}]`, `{"a":[1,2,3]}`, false},
+		{"replace field", `{"a":1}`, `[{"op":"replace","path":"/a","value":2}]`, `{"a":2}`, false},
+		{"replace missing field", `{"a":1}`, `[{"op":"replace","path":"/b","value":2}]`, ``, true},
+		{"remove field", `{"a":1,"b":2}`, `[{"op":"remove","path":"/b"}]`, `{"a":1}`, false},
+		{"remove array item", `{"a":[1,2,3]}`, `[{"op":"remove","path":"/a/1"}]`, `{"a":[1,3]}`, false},
+		{"move field", `{"a":{"b":1}}`, `[{"op":"move","from":"/a/b","path":"/c"}]`, `{"a":{},"c":1}`, false},
+		{"copy field", `{"a":{"b":1}}`, `[{"op":"copy","from":"/a","path":"/c"}]`, `{"a":{"b":1},"c":{"b":1}}`, false},
+		{"escaped pointer", `{"a/b":1,"c~d":2}`, `[{"op":"remove","path":"/a~1b"},{"op":"remove","path":"/c~0d"}]`, `{}`, false},
+		{"test passes", `{"a":1}`, `[{"op":"test","path":"/a","value":1},{"op":"add","path":"/b","value":2}]`, `{"a":1,"b":2}`, false},
+		{"test fails", `{"a":1}`, `[{"op":"test","path":"/a","value":2}]`, ``, true},
+		{"invalid pointer", `{"a":1}`, `[{"op":"remove","path":"a"}]`, ``, true},
+		{"invalid array index", `{"a":[1]}`, `[{"op":"remove","path":"/a/01"}]`, ``, true},
+		{"value required", `{"a":1}`, `[{"op":"add","path":"/b"}]`, ``, true},
+		{"unsupported op", `{"a":1}`, `[{"op":"merge","path":"/a","value":1}]`, ``, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			operations := make([]JsonPatchOperation, 0)
+			if err := json.Unmarshal([]byte(tt.operations), &operations); err != nil {
+				t.Fatal(err)
+			}
+			got, err := ApplyJsonPatch(decodeJsonValue(t, tt.doc), operations)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if want := decodeJsonValue(t, tt.want); !reflect.DeepEqual(got, want) {
+				t.Fatalf("got %v,want %v", got, want)
+			}
+		})
+	}
+}
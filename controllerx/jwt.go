@@ -4,17 +4,20 @@ import (
 	"sync"
 
 	"github.com/abmpio/irisx/casdoor"
+	"github.com/casdoor/casdoor-go-sdk/casdoorsdk"
+	"github.com/kataras/iris/v12"
 )
 
 var (
-	_casdoorOptions     casdoor.CasdoorOptions
-	_casdoorM           *casdoor.CasdoorMiddleware
-	_mustAuthenticatedM *casdoor.MustAuthenticated
-	_sync               sync.Once
+	_casdoorOptions         casdoor.CasdoorOptions
+	_casdoorM               *casdoor.CasdoorMiddleware
+	_mustAuthenticatedM     *casdoor.MustAuthenticated
+	_casdoorSync            sync.Once
+	_mustAuthenticatedMSync sync.Once
 )
 
 func GetCasdoorMiddleware() *casdoor.CasdoorMiddleware {
-	_sync.Do(func() {
+	_casdoorSync.Do(func() {
 		_casdoorOptions = *casdoor.InitCasdoorSdk()
 		_casdoorM = casdoor.NewCasdoorMiddleware(_casdoorOptions)
 	})
@@ -22,8 +25,25 @@ func GetCasdoorMiddleware() *casdoor.CasdoorMiddleware {
 }
 
 func GetMustAuthenticatedMiddleware() *casdoor.MustAuthenticated {
-	_sync.Do(func() {
+	_mustAuthenticatedMSync.Do(func() {
 		_mustAuthenticatedM = casdoor.NewMustAuthenticated()
 	})
 	return _mustAuthenticatedM
 }
+
+// new authorization middleware which reads the claims set by casdoor middleware
+func GetMustAuthorizedMiddleware(authorization casdoor.Authorization) *casdoor.MustAuthorized {
+	return casdoor.NewMustAuthorized(func(ctx iris.Context) *casdoorsdk.Claims {
+		return GetCasdoorMiddleware().GetUserClaims(ctx)
+	}, authorization)
+}
+
+// new casbin enforce middleware which reads the claims set by casdoor middleware
+func GetEnforceMiddleware(options casdoor.EnforceOptions) *casdoor.EnforceMiddleware {
+	if options.ClaimsGetter == nil {
+		options.ClaimsGetter = func(ctx iris.Context) *casdoorsdk.Claims {
+			return GetCasdoorMiddleware().GetUserClaims(ctx)
+		}
+	}
+	return casdoor.NewEnforceMiddleware(options)
+}
@@ -0,0 +1,80 @@
+package controllerx
+
+import (
+	"github.com/abmpio/entity"
+	"github.com/kataras/iris/v12"
+)
+
+const (
+	DefaultMaxPageSize = 1000
+	// default max number of items returned by All
+	DefaultAllMaxSize = 10000
+	// response header set to true when the items of All or the groups of Aggregate are truncated to the max size,
+	// the total of All is the number of all matched items then
+	AllTruncatedHeaderName = "X-Truncated"
+)
+
+// PaginationPolicy limits the page size requested by client
+type PaginationPolicy struct {
+	// used when client does not send size,entity.PaginationDefaultSize if it is 0
+	DefaultSize int
+	// max page size,DefaultMaxPageSize if it is 0,no limit if it is negative
+	MaxSize int
+	// max number of items returned by All,DefaultAllMaxSize if it is 0,no limit if it is negative
+	AllMaxSize int
+	// reject the oversized request with 400 instead of clamping it
+	RejectOversized bool
+}
+
+func (p *PaginationPolicy) defaultSize() int {
+	if p.DefaultSize > 0 {
+		return p.DefaultSize
+	}
+	return entity.PaginationDefaultSize
+}
+
+func (p *PaginationPolicy) maxSize() int {
+	if p.MaxSize == 0 {
+		return DefaultMaxPageSize
+	}
+	return p.MaxSize
+}
+
+func (p *PaginationPolicy) allMaxSize() int {
+	if p.AllMaxSize == 0 {
+		return DefaultAllMaxSize
+	}
+	return p.AllMaxSize
+}
+
+// normalize the page and size requested by client,
+// the oversized size is clamped to the max size or rejected by RejectOversized
+func (p *PaginationPolicy) Normalize(page int, size int) (int, int, error) {
+	if page <= 0 {
+		page = entity.PaginationDefaultPage
+	}
+	if size <= 0 {
+		size = p.defaultSize()
+	}
+	maxSize := p.maxSize()
+	if maxSize > 0 && size > maxSize {
+		if p.RejectOversized {
+			return 0, 0, NewHttpErrorf(iris.StatusBadRequest, "page size %d exceeds the max page size %d", size, maxSize)
+		}
+		size = maxSize
+	}
+	return page, size, nil
+}
+
+// check the number of items of All,returns the number of items to keep and whether the items are truncated,
+// the extra item fetched to detect overflow is dropped
+func (p *PaginationPolicy) limitAll(count int) (int, bool, error) {
+	maxSize := p.allMaxSize()
+	if maxSize <= 0 || count <= maxSize {
+		return count, false, nil
+	}
+	if p.RejectOversized {
+		return 0, false, NewHttpErrorf(iris.StatusBadRequest, "the number of items exceeds %d,please use the paginated list", maxSize)
+	}
+	return maxSize, true, nil
+}
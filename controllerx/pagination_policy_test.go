@@ -0,0 +1,78 @@
+package controllerx
+
+import (
+	"testing"
+
+	"github.com/abmpio/entity"
+)
+
+func TestPaginationPolicyNormalize(t *testing.T) {
+	tests := []struct {
+		name     string
+		policy   PaginationPolicy
+		page     int
+		size     int
+		wantPage int
+		wantSize int
+		wantErr  bool
+	}{
+		{"defaults", PaginationPolicy{}, 0, 0, entity.PaginationDefaultPage, entity.PaginationDefaultSize, false},
+		{"default size", PaginationPolicy{DefaultSize: 50}, 2, -1, 2, 50, false},
+		{"within max", PaginationPolicy{}, 3, DefaultMaxPageSize, 3, DefaultMaxPageSize, false},
+		{"clamped to default max", PaginationPolicy{}, 1, DefaultMaxPageSize + 1, 1, DefaultMaxPageSize, false},
+		{"clamped to max", PaginationPolicy{MaxSize: 100}, 1, 101, 1, 100, false},
+		{"rejected", PaginationPolicy{MaxSize: 100, RejectOversized: true}, 1, 101, 0, 0, true},
+		{"no limit", PaginationPolicy{MaxSize: -1}, 1, 100000, 1, 100000, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			page, size, err := tt.policy.Normalize(tt.page, tt.size)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %d,%d", page, size)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if page != tt.wantPage || size != tt.wantSize {
+				t.Fatalf("got %d,%d,want %d,%d", page, size, tt.wantPage, tt.wantSize)
+			}
+		})
+	}
+}
+
+func TestPaginationPolicyLimitAll(t *testing.T) {
+	tests := []struct {
+		name          string
+		policy        PaginationPolicy
+		count         int
+		wantCount     int
+		wantTruncated bool
+		wantErr       bool
+	}{
+		{"within default max", PaginationPolicy{}, DefaultAllMaxSize, DefaultAllMaxSize, false, false},
+		{"truncated to default max", PaginationPolicy{}, DefaultAllMaxSize + 1, DefaultAllMaxSize, true, false},
+		{"truncated to max", PaginationPolicy{AllMaxSize: 10}, 11, 10, true, false},
+		{"rejected", PaginationPolicy{AllMaxSize: 10, RejectOversized: true}, 11, 0, false, true},
+		{"no limit", PaginationPolicy{AllMaxSize: -1}, 100000, 100000, false, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			count, truncated, err := tt.policy.limitAll(tt.count)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %d", count)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if count != tt.wantCount || truncated != tt.wantTruncated {
+				t.Fatalf("got %d,%v,want %d,%v", count, truncated, tt.wantCount, tt.wantTruncated)
+			}
+		})
+	}
+}
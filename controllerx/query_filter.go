@@ -0,0 +1,165 @@
+package controllerx
+
+import (
+	"fmt"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+const (
+	// query parameter prefix of filter,e.g. ?filter[status][in]=a,b&filter[age][gte]=18
+	QueryFilterParamName = "filter"
+	// separator of the values of in,nin and between
+	QueryFilterValueSeparator = ","
+)
+
+// parse the bracket notation filter of query string,
+// filter[field]=v is an eq condition,filter[field][op]=v uses the op,
+// the values of in,nin and between are separated by comma,all conditions are combined by and
+func ParseQueryFilter(values url.Values) (*FilterExpression, error) {
+	keys := make([]string, 0)
+	for eachKey := range values {
+		if strings.HasPrefix(eachKey, QueryFilterParamName+"[") {
+			keys = append(keys, eachKey)
+		}
+	}
+	if len(keys) <= 0 {
+		return nil, nil
+	}
+	sort.Strings(keys)
+
+	expression := &FilterExpression{
+		And: make([]*FilterExpression, 0, len(keys)),
+	}
+	for _, eachKey := range keys {
+		segments, err := parseQueryFilterKey(eachKey)
+		if err != nil {
+			return nil, err
+		}
+		field := segments[0]
+		op := FilterOperatorEq
+		if len(segments) == 2 {
+			op = FilterOperator(segments[1])
+		}
+		for _, eachValue := range values[eachKey] {
+			var value interface{} = eachValue
+			switch op {
+			case FilterOperatorIn, FilterOperatorNin, FilterOperatorBetween:
+				list := make([]interface{}, 0)
+				for _, eachItem := range strings.Split(eachValue, QueryFilterValueSeparator) {
+					list = append(list, eachItem)
+				}
+				value = list
+			}
+			expression.And = append(expression.And, &FilterExpression{
+				Field: field,
+				Op:    op,
+				Value: value,
+			})
+		}
+	}
+	return expression, nil
+}
+
+// parse filter[field] or filter[field][op] to segments
+func parseQueryFilterKey(key string) ([]string, error) {
+	rest := strings.TrimPrefix(key, QueryFilterParamName)
+	segments := make([]string, 0, 2)
+	for len(rest) > 0 {
+		if rest[0] != '[' {
+			return nil, newFilterError("", "invalid filter parameter:%s", key)
+		}
+		end := strings.IndexByte(rest, ']')
+		if end < 0 {
+			return nil, newFilterError("", "invalid filter parameter:%s", key)
+		}
+		segments = append(segments, rest[1:end])
+		rest = rest[end+1:]
+	}
+	if len(segments) <= 0 || len(segments) > 2 || len(segments[0]) <= 0 {
+		return nil, newFilterError("", "invalid filter parameter:%s,must be filter[field] or filter[field][op]", key)
+	}
+	return segments, nil
+}
+
+var (
+	_objectIdType = reflect.TypeOf(primitive.ObjectID{})
+	_timeType     = reflect.TypeOf(time.Time{})
+	_dateTimeType = reflect.TypeOf(primitive.DateTime(0))
+)
+
+// convert the string value to fieldType,values of the other types are returned as is,
+// the element type is used for slice field
+func CoerceFilterValue(fieldType reflect.Type, value interface{}) (interface{}, error) {
+	if list, ok := value.([]interface{}); ok {
+		converted := make([]interface{}, 0, len(list))
+		for _, eachValue := range list {
+			v, err := CoerceFilterValue(fieldType, eachValue)
+			if err != nil {
+				return nil, err
+			}
+			converted = append(converted, v)
+		}
+		return converted, nil
+	}
+	s, ok := value.(string)
+	if !ok || fieldType == nil {
+		return value, nil
+	}
+	for fieldType.Kind() == reflect.Pointer {
+		fieldType = fieldType.Elem()
+	}
+	if (fieldType.Kind() == reflect.Slice || fieldType.Kind() == reflect.Array) && fieldType != _objectIdType && fieldType.Elem().Kind() != reflect.Uint8 {
+		return CoerceFilterValue(fieldType.Elem(), value)
+	}
+
+	switch fieldType {
+	case _objectIdType:
+		return primitive.ObjectIDFromHex(s)
+	case _timeType:
+		return parseFilterTime(s)
+	case _dateTimeType:
+		t, err := parseFilterTime(s)
+		if err != nil {
+			return nil, err
+		}
+		return primitive.NewDateTimeFromTime(t), nil
+	}
+	switch fieldType.Kind() {
+	case reflect.Bool:
+		return strconv.ParseBool(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.ParseInt(s, 10, 64)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		v, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			return nil, err
+		}
+		// mongo has no unsigned integer,the values are stored as int64
+		if v > math.MaxInt64 {
+			return nil, fmt.Errorf("value %s is out of range,max is %d", s, int64(math.MaxInt64))
+		}
+		return int64(v), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.ParseFloat(s, 64)
+	}
+	return value, nil
+}
+
+// parse time in RFC3339 or date format
+func parseFilterTime(s string) (time.Time, error) {
+	for _, eachLayout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05", "2006-01-02"} {
+		t, err := time.Parse(eachLayout, s)
+		if err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time:%s,must be RFC3339 or yyyy-MM-dd format", s)
+}
@@ -0,0 +1,126 @@
+package controllerx
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestParseQueryFilter(t *testing.T) {
+	tests := []struct {
+		name    string
+		query   string
+		want    *FilterExpression
+		wantErr bool
+	}{
+		{"no filter", "page=1&filter=%7B%7D", nil, false},
+		{
+			"eq",
+			"filter[name]=a",
+			&FilterExpression{And: []*FilterExpression{{Field: "name", Op: FilterOperatorEq, Value: "a"}}},
+			false,
+		},
+		{
+			"operator and sorted keys",
+			"filter[status][in]=a,b&filter[age][gte]=18",
+			&FilterExpression{And: []*FilterExpression{
+				{Field: "age", Op: FilterOperatorGte, Value: "18"},
+				{Field: "status", Op: FilterOperatorIn, Value: []interface{}{"a", "b"}},
+			}},
+			false,
+		},
+		{
+			"repeated parameter",
+			"filter[age][between]=1,2&filter[age][between]=3,4",
+			&FilterExpression{And: []*FilterExpression{
+				{Field: "age", Op: FilterOperatorBetween, Value: []interface{}{"1", "2"}},
+				{Field: "age", Op: FilterOperatorBetween, Value: []interface{}{"3", "4"}},
+			}},
+			false,
+		},
+		{
+			"nested field",
+			"filter[profile.city]=x",
+			&FilterExpression{And: []*FilterExpression{{Field: "profile.city", Op: FilterOperatorEq, Value: "x"}}},
+			false,
+		},
+		{"empty field", "filter[]=a", nil, true},
+		{"too many segments", "filter[a][eq][x]=a", nil, true},
+		{"missing bracket", "filter[a=1", nil, true},
+		{"text after segment", "filter[a]x=1", nil, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			values, err := url.ParseQuery(tt.query)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := ParseQueryFilter(values)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %+v,want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCoerceFilterValue(t *testing.T) {
+	objectId := primitive.NewObjectID()
+	day := time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		fieldType reflect.Type
+		value     interface{}
+		want      interface{}
+		wantErr   bool
+	}{
+		{"unknown field", nil, "1", "1", false},
+		{"string field", reflect.TypeOf(""), "1", "1", false},
+		{"non string value", reflect.TypeOf(0), float64(1), float64(1), false},
+		{"int", reflect.TypeOf(0), "12", int64(12), false},
+		{"invalid int", reflect.TypeOf(0), "a", nil, true},
+		{"uint", reflect.TypeOf(uint32(0)), "12", int64(12), false},
+		{"max uint64 in range", reflect.TypeOf(uint64(0)), "9223372036854775807", int64(9223372036854775807), false},
+		{"uint64 overflow", reflect.TypeOf(uint64(0)), "9223372036854775808", nil, true},
+		{"float", reflect.TypeOf(float32(0)), "1.5", 1.5, false},
+		{"bool", reflect.TypeOf(false), "true", true, false},
+		{"pointer", reflect.TypeOf(new(int)), "3", int64(3), false},
+		{"slice element", reflect.TypeOf([]int{}), "3", int64(3), false},
+		{"list", reflect.TypeOf(0), []interface{}{"1", "2"}, []interface{}{int64(1), int64(2)}, false},
+		{"object id", reflect.TypeOf(primitive.ObjectID{}), objectId.Hex(), objectId, false},
+		{"invalid object id", reflect.TypeOf(primitive.ObjectID{}), "x", nil, true},
+		{"date", reflect.TypeOf(time.Time{}), "2024-01-02", day, false},
+		{"rfc3339", reflect.TypeOf(time.Time{}), "2024-01-02T00:00:00Z", day, false},
+		{"datetime", reflect.TypeOf(primitive.DateTime(0)), "2024-01-02", primitive.NewDateTimeFromTime(day), false},
+		{"invalid time", reflect.TypeOf(time.Time{}), "yesterday", nil, true},
+		{"bytes", reflect.TypeOf([]byte{}), "ab", "ab", false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := CoerceFilterValue(tt.fieldType, tt.value)
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected error,got %v", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("got %#v,want %#v", got, tt.want)
+			}
+		})
+	}
+}
@@ -10,6 +10,18 @@ import (
 type SearchInput struct {
 	controller.Pagination
 	Filter map[string]interface{} `json:",inline"`
+	// conditions combined with Filter by and,the fields must be filterable by controller
+	Where *FilterExpression `json:"where"`
+	// the returned fields,the field starts with "-" is excluded
+	Fields []string `json:"fields"`
+	// the relations to expand
+	Expand []string `json:"expand"`
+	// include the soft deleted items
+	IncludeDeleted bool `json:"includeDeleted"`
+	// use cursor mode when it is not nil,empty cursor gets the first page
+	Cursor *string `json:"cursor"`
+	// count the total in cursor mode
+	IncludeTotal bool `json:"includeTotal"`
 
 	SortInput
 }
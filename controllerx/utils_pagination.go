@@ -35,3 +35,16 @@ func GetBatchRequestPayload(ctx iris.Context) (payload entity.BatchRequestPayloa
 	}
 	return payload, err
 }
+
+// IdsRequestPayload is the payload of batch operations,
+// ids are parsed by the id codec of controller
+type IdsRequestPayload struct {
+	Ids []string `json:"ids"`
+}
+
+func GetIdsRequestPayload(ctx iris.Context) (payload IdsRequestPayload, err error) {
+	if err := ctx.ReadJSON(&payload); err != nil {
+		return payload, err
+	}
+	return payload, err
+}